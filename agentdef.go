@@ -0,0 +1,401 @@
+package apiai
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const defaultIntentPriority = 500000
+
+// AgentDefinition describes the intents and entities of an agent in YAML, so
+// they can be kept in version control and applied with PlanAgent.
+type AgentDefinition struct {
+	Entities []EntityDefinition `yaml:"entities"`
+	Intents  []IntentDefinition `yaml:"intents"`
+}
+
+type EntityDefinition struct {
+	Name               string  `yaml:"name"`
+	IsEnum             bool    `yaml:"isEnum"`
+	AutomatedExpansion bool    `yaml:"automatedExpansion"`
+	Entries            []Entry `yaml:"entries"`
+}
+
+type ParameterDefinition struct {
+	Name         string   `yaml:"name"`
+	DataType     string   `yaml:"dataType"`
+	Value        string   `yaml:"value"`
+	DefaultValue string   `yaml:"defaultValue"`
+	Required     bool     `yaml:"required"`
+	Prompts      []string `yaml:"prompts"`
+	IsList       bool     `yaml:"isList"`
+}
+
+type IntentDefinition struct {
+	Name                  string                `yaml:"name"`
+	UserSays              []string              `yaml:"userSays"`
+	Contexts              []string              `yaml:"contexts"`
+	OutputContexts        []Context             `yaml:"outputContexts"`
+	ResetContexts         bool                  `yaml:"resetContexts"`
+	Action                string                `yaml:"action"`
+	Parameters            []ParameterDefinition `yaml:"parameters"`
+	Speech                []string              `yaml:"speech"`
+	Events                []string              `yaml:"events"`
+	Priority              *int                  `yaml:"priority"`
+	Fallback              bool                  `yaml:"fallback"`
+	Webhook               bool                  `yaml:"webhook"`
+	WebhookForSlotFilling bool                  `yaml:"webhookForSlotFilling"`
+}
+
+func LoadAgentDefinition(r io.Reader) (*AgentDefinition, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var def AgentDefinition
+	if err := yaml.UnmarshalStrict(b, &def); err != nil {
+		return nil, fmt.Errorf("apiai: invalid agent definition, %v", err)
+	}
+	return &def, nil
+}
+
+func LoadAgentDefinitionFile(path string) (*AgentDefinition, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadAgentDefinition(f)
+}
+
+// BuildEntities returns the defined entities, sorted so that composite
+// entities come after the entities they reference.
+func (d *AgentDefinition) BuildEntities() ([]Entity, error) {
+	var entities []Entity
+	for _, e := range d.Entities {
+		b := NewEntityBuilder(e.Name)
+		for _, other := range d.Entities {
+			b.KnownNames(other.Name)
+		}
+		if e.IsEnum {
+			b.Enum()
+		}
+		if e.AutomatedExpansion {
+			b.AutomatedExpansion()
+		}
+		for _, entry := range e.Entries {
+			b.Entry(entry.Value, entry.Synonyms...)
+		}
+		entity, err := b.Build()
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, entity)
+	}
+	return ResolveEntityDependencies(entities)
+}
+
+func (d *AgentDefinition) BuildIntents() ([]Intent, error) {
+	var intents []Intent
+	for _, i := range d.Intents {
+		b := NewIntentBuilder(i.Name).
+			UserSays(i.UserSays...).
+			InputContexts(i.Contexts...).
+			Action(i.Action).
+			Speech(i.Speech...).
+			Priority(defaultIntentPriority)
+		for _, p := range i.Parameters {
+			opts := []ParamOption{}
+			if p.Required {
+				opts = append(opts, Required(p.Prompts...))
+			}
+			if p.IsList {
+				opts = append(opts, IsList())
+			}
+			if p.DefaultValue != "" {
+				opts = append(opts, DefaultValue(p.DefaultValue))
+			}
+			b.Param(p.Name, p.DataType, opts...)
+		}
+		for _, c := range i.OutputContexts {
+			b.OutputContext(c.Name, c.Lifespan)
+		}
+		for _, e := range i.Events {
+			b.Event(e)
+		}
+		if i.Priority != nil {
+			b.Priority(*i.Priority)
+		}
+		if i.ResetContexts {
+			b.ResetContexts()
+		}
+		if i.Fallback {
+			b.Fallback()
+		}
+		if i.Webhook {
+			b.Webhook(i.WebhookForSlotFilling)
+		}
+		intent, err := b.Build()
+		if err != nil {
+			return nil, err
+		}
+		intent.Auto = true
+		for j, p := range i.Parameters {
+			if p.Value != "" {
+				intent.Responses[0].Params[j].Value = p.Value
+			}
+		}
+		intents = append(intents, intent)
+	}
+	return intents, nil
+}
+
+type PlanAction string
+
+const (
+	PlanCreate PlanAction = "create"
+	PlanUpdate PlanAction = "update"
+	PlanDelete PlanAction = "delete"
+)
+
+type PlanChange struct {
+	Action PlanAction
+	Id     string
+	Name   string
+	Intent *Intent
+	Entity *Entity
+	// Fields lists what changes in updates.
+	Fields []FieldChange
+}
+
+func (c PlanChange) Kind() string {
+	if c.Entity != nil {
+		return "entity"
+	}
+	return "intent"
+}
+
+// AgentPlan lists the changes needed to make an agent match a definition.
+type AgentPlan struct {
+	Changes []PlanChange
+}
+
+func (p *AgentPlan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+func (p *AgentPlan) String() string {
+	symbols := map[PlanAction]string{PlanCreate: "+", PlanUpdate: "~", PlanDelete: "-"}
+	counts := map[PlanAction]int{}
+	buf := new(bytes.Buffer)
+	for _, c := range p.Changes {
+		counts[c.Action]++
+		fmt.Fprintf(buf, "%s %s %s\n", symbols[c.Action], c.Kind(), c.Name)
+	}
+	fmt.Fprintf(buf, "Plan: %d to create, %d to update, %d to delete.\n", counts[PlanCreate], counts[PlanUpdate], counts[PlanDelete])
+	return buf.String()
+}
+
+// PlanAgent compares the definition with the agent, matching intents and
+// entities by name. Intents and entities missing from the definition are only
+// deleted when prune is set.
+func (c *ApiClient) PlanAgent(def *AgentDefinition, prune bool) (*AgentPlan, error) {
+	entities, err := def.BuildEntities()
+	if err != nil {
+		return nil, err
+	}
+	intents, err := def.BuildIntents()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &AgentPlan{}
+	remoteEntities, err := c.GetEntities()
+	if err != nil {
+		return nil, err
+	}
+	entityIds := map[string]string{}
+	for _, e := range remoteEntities {
+		entityIds[e.Name] = e.Id
+	}
+	for i := range entities {
+		desired := entities[i]
+		id, ok := entityIds[desired.Name]
+		if !ok {
+			plan.Changes = append(plan.Changes, PlanChange{Action: PlanCreate, Name: desired.Name, Entity: &desired})
+			continue
+		}
+		current, err := c.GetEntity(id)
+		if err != nil {
+			return nil, err
+		}
+		desired.Id = id
+		if fields := DiffEntityFields(*current, desired); len(fields) > 0 {
+			plan.Changes = append(plan.Changes, PlanChange{Action: PlanUpdate, Id: id, Name: desired.Name, Entity: &desired, Fields: fields})
+		}
+	}
+
+	remoteIntents, err := c.GetIntents()
+	if err != nil {
+		return nil, err
+	}
+	intentIds := map[string]string{}
+	for _, i := range remoteIntents {
+		intentIds[i.Name] = i.Id
+	}
+	for i := range intents {
+		desired := intents[i]
+		id, ok := intentIds[desired.Name]
+		if !ok {
+			plan.Changes = append(plan.Changes, PlanChange{Action: PlanCreate, Name: desired.Name, Intent: &desired})
+			continue
+		}
+		current, err := c.GetIntent(id)
+		if err != nil {
+			return nil, err
+		}
+		desired.Id = id
+		if fields := DiffIntentFields(*current, desired); len(fields) > 0 {
+			plan.Changes = append(plan.Changes, PlanChange{Action: PlanUpdate, Id: id, Name: desired.Name, Intent: &desired, Fields: fields})
+		}
+	}
+
+	if prune {
+		definedIntents := map[string]bool{}
+		for _, i := range intents {
+			definedIntents[i.Name] = true
+		}
+		for _, i := range remoteIntents {
+			if !definedIntents[i.Name] {
+				plan.Changes = append(plan.Changes, PlanChange{Action: PlanDelete, Id: i.Id, Name: i.Name, Intent: &Intent{Id: i.Id, Name: i.Name}})
+			}
+		}
+		definedEntities := map[string]bool{}
+		for _, e := range entities {
+			definedEntities[e.Name] = true
+		}
+		for _, e := range remoteEntities {
+			if !definedEntities[e.Name] {
+				plan.Changes = append(plan.Changes, PlanChange{Action: PlanDelete, Id: e.Id, Name: e.Name, Entity: &Entity{Id: e.Id, Name: e.Name}})
+			}
+		}
+	}
+	return plan, nil
+}
+
+// ApplyAgentPlan applies the changes in order: entities before the intents
+// that use them, and intents are deleted before the entities they used.
+func (c *ApiClient) ApplyAgentPlan(plan *AgentPlan) error {
+	for _, change := range plan.Changes {
+		var err error
+		switch {
+		case change.Action == PlanCreate && change.Entity != nil:
+			_, err = c.CreateEntity(*change.Entity)
+		case change.Action == PlanUpdate && change.Entity != nil:
+			err = c.UpdateEntity(change.Id, *change.Entity)
+		case change.Action == PlanDelete && change.Entity != nil:
+			err = c.DeleteEntity(change.Id)
+		case change.Action == PlanCreate:
+			_, err = c.CreateIntent(*change.Intent)
+		case change.Action == PlanUpdate:
+			err = c.UpdateIntent(change.Id, *change.Intent)
+		case change.Action == PlanDelete:
+			err = c.DeleteIntent(change.Id)
+		}
+		if err != nil {
+			return fmt.Errorf("apiai: error on %s %s %q, %w", change.Action, change.Kind(), change.Name, err)
+		}
+	}
+	return nil
+}
+
+// EntitiesEqual compares two entities ignoring their ids and the order of
+// entries and synonyms.
+func EntitiesEqual(a, b Entity) bool {
+	return len(DiffEntityFields(a, b)) == 0
+}
+
+// IntentsEqual compares two intents ignoring ids, counters and the order of
+// training phrases and contexts.
+func IntentsEqual(a, b Intent) bool {
+	return len(DiffIntentFields(a, b)) == 0
+}
+
+func canonicalEntity(e Entity) Entity {
+	e.Id = ""
+	entries := make([]Entry, len(e.Entries))
+	for i, entry := range e.Entries {
+		synonyms := append([]string{}, entry.Synonyms...)
+		sort.Strings(synonyms)
+		entries[i] = Entry{Value: entry.Value, Synonyms: synonyms}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Value < entries[j].Value })
+	e.Entries = entries
+	return e
+}
+
+func canonicalIntent(i Intent) Intent {
+	i.Id = ""
+	i.Contexts = sortedStrings(i.Contexts)
+	i.Templates = nil
+
+	userSays := make([]UserSays, len(i.UserSays))
+	for j, us := range i.UserSays {
+		data := make([]Data, len(us.Data))
+		copy(data, us.Data)
+		userSays[j] = UserSays{Data: data, IsTemplate: us.IsTemplate}
+	}
+	sort.Slice(userSays, func(a, b int) bool { return userSays[a].String() < userSays[b].String() })
+	i.UserSays = userSays
+
+	responses := make([]IntentResponse, len(i.Responses))
+	for j, r := range i.Responses {
+		contexts := make([]Context, len(r.AffectedContexts))
+		for k, c := range r.AffectedContexts {
+			contexts[k] = Context{Name: strings.ToLower(c.Name), Lifespan: c.Lifespan}
+		}
+		sort.Slice(contexts, func(a, b int) bool { return contexts[a].Name < contexts[b].Name })
+		r.AffectedContexts = contexts
+		params := make([]IntentParameter, len(r.Params))
+		for k, p := range r.Params {
+			p.Prompts = append([]string{}, p.Prompts...)
+			params[k] = p
+		}
+		r.Params = params
+		messages := make([]Message, len(r.Messages))
+		for k, m := range r.Messages {
+			m.Type = fmt.Sprint(m.Type)
+			if len(m.Buttons) == 0 {
+				m.Buttons = nil
+			}
+			if len(m.Replies) == 0 {
+				m.Replies = nil
+			}
+			messages[k] = m
+		}
+		r.Messages = messages
+		responses[j] = r
+	}
+	i.Responses = responses
+
+	events := make([]Event, len(i.Events))
+	for j, e := range i.Events {
+		events[j] = Event{Name: e.Name}
+	}
+	sort.Slice(events, func(a, b int) bool { return events[a].Name < events[b].Name })
+	i.Events = events
+	return i
+}
+
+func sortedStrings(values []string) []string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
+}
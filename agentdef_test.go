@@ -0,0 +1,162 @@
+package apiai
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const agentDefinitionYAML = `
+entities:
+  - name: money
+    isEnum: true
+    entries:
+      - value: "@sys.number:amount @currency:currency"
+  - name: currency
+    entries:
+      - value: EUR
+        synonyms: [euro, euros]
+      - value: USD
+        synonyms: [dollar, dollars]
+intents:
+  - name: pay
+    userSays:
+      - "pay @money:money"
+    action: payment.make
+    parameters:
+      - name: money
+        dataType: "@money"
+        required: true
+        prompts: ["How much?"]
+    speech: ["Paying $money"]
+    outputContexts:
+      - name: payment
+        lifespan: 2
+  - name: greet
+    userSays: ["hello", "hi"]
+    speech: ["Hello!"]
+    events: [WELCOME]
+`
+
+func TestLoadAgentDefinition(t *testing.T) {
+	assert := assert.New(t)
+
+	def, err := LoadAgentDefinition(strings.NewReader(agentDefinitionYAML))
+	assert.Nil(err)
+
+	entities, err := def.BuildEntities()
+	assert.Nil(err)
+	assert.Equal("currency", entities[0].Name, "referenced entities come first")
+	assert.Equal("money", entities[1].Name)
+	assert.True(entities[1].IsEnum)
+
+	intents, err := def.BuildIntents()
+	assert.Nil(err)
+	assert.Len(intents, 2)
+	assert.Equal(defaultIntentPriority, intents[0].Priority)
+	assert.True(intents[0].Auto)
+	assert.Equal("payment.make", intents[0].Responses[0].Action)
+	assert.Equal([]Event{{Name: "WELCOME"}}, intents[1].Events)
+
+	_, err = LoadAgentDefinition(strings.NewReader("intents:\n  - name: a\n    usersays: [hi]\n"))
+	assert.NotNil(err)
+
+	def, _ = LoadAgentDefinition(strings.NewReader("intents:\n  - name: a\n    userSays: ['hi @sys.any:thing']\n"))
+	_, err = def.BuildIntents()
+	assert.EqualError(err, `apiai: invalid intent "a": alias "thing" has no parameter`)
+}
+
+func TestPlanAgent(t *testing.T) {
+	c, err := NewClient(&ClientConfig{Token: "fakeToken"})
+	if err != nil {
+		t.FailNow()
+	}
+	assert := assert.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", c.buildUrl("entities", nil), httpmock.NewStringResponder(200, `[
+  {"id": "e1", "name": "currency"},
+  {"id": "e2", "name": "appliances"}
+]`))
+	httpmock.RegisterResponder("GET", c.buildUrl("entities/e1", nil), httpmock.NewStringResponder(200, `{
+  "id": "e1",
+  "name": "currency",
+  "isEnum": false,
+  "entries": [
+    {"value": "USD", "synonyms": ["dollars", "dollar"]},
+    {"value": "EUR", "synonyms": ["euro", "euros"]}
+  ]
+}`))
+	httpmock.RegisterResponder("GET", c.buildUrl("intents", nil), httpmock.NewStringResponder(200, `[
+  {"id": "i1", "name": "greet"},
+  {"id": "i2", "name": "pay"},
+  {"id": "i3", "name": "Default Fallback Intent"}
+]`))
+	httpmock.RegisterResponder("GET", c.buildUrl("intents/i1", nil), httpmock.NewStringResponder(200, `{
+  "id": "i1",
+  "name": "greet",
+  "auto": true,
+  "contexts": [],
+  "userSays": [
+    {"id": "u1", "data": [{"text": "hi"}], "isTemplate": false, "count": 3},
+    {"id": "u2", "data": [{"text": "hello"}], "isTemplate": false, "count": 0}
+  ],
+  "responses": [{"action": "", "resetContexts": false, "affectedContexts": [], "parameters": [], "messages": [{"type": 0, "speech": "Hello!"}]}],
+  "priority": 500000,
+  "events": [{"name": "WELCOME"}]
+}`))
+	httpmock.RegisterResponder("GET", c.buildUrl("intents/i2", nil), httpmock.NewStringResponder(200, `{
+  "id": "i2",
+  "name": "pay",
+  "auto": true,
+  "userSays": [{"id": "u3", "data": [{"text": "pay "}, {"text": "@money:money", "meta": "@money", "alias": "money"}], "isTemplate": true}],
+  "responses": [{"action": "payment.make", "messages": [{"type": 0, "speech": "Paying $money"}]}],
+  "priority": 500000
+}`))
+
+	def, err := LoadAgentDefinition(strings.NewReader(agentDefinitionYAML))
+	assert.Nil(err)
+
+	plan, err := c.PlanAgent(def, false)
+	assert.Nil(err)
+	assert.Equal("+ entity money\n~ intent pay\nPlan: 1 to create, 1 to update, 0 to delete.\n", plan.String())
+
+	plan, err = c.PlanAgent(def, true)
+	assert.Nil(err)
+	assert.Equal(`+ entity money
+~ intent pay
+- intent Default Fallback Intent
+- entity appliances
+Plan: 1 to create, 1 to update, 2 to delete.
+`, plan.String())
+
+	var calls []string
+	recorder := func(req *http.Request) (*http.Response, error) {
+		calls = append(calls, req.Method+" "+req.URL.Path)
+		return httpmock.NewStringResponse(200, `{"id": "new", "status": {"code": 200}}`), nil
+	}
+	httpmock.RegisterResponder("POST", c.buildUrl("entities", nil), recorder)
+	httpmock.RegisterResponder("PUT", c.buildUrl("intents/i2", nil), recorder)
+	httpmock.RegisterResponder("DELETE", c.buildUrl("intents/i3", nil), recorder)
+	httpmock.RegisterResponder("DELETE", c.buildUrl("entities/e2", nil), recorder)
+
+	assert.Nil(c.ApplyAgentPlan(plan))
+	assert.Equal([]string{
+		"POST /v1/entities",
+		"PUT /v1/intents/i2",
+		"DELETE /v1/intents/i3",
+		"DELETE /v1/entities/e2",
+	}, calls)
+
+	httpmock.RegisterResponder("DELETE", c.buildUrl("entities/e2", nil), httpmock.NewStringResponder(http.StatusBadRequest, `{}`))
+	err = c.ApplyAgentPlan(&AgentPlan{Changes: []PlanChange{{Action: PlanDelete, Id: "e2", Name: "appliances", Entity: &Entity{}}}})
+	assert.EqualError(err, `apiai: error on delete entity "appliances", apiai: wops something happens because status code is 400`)
+	var statusErr *StatusError
+	assert.True(errors.As(err, &statusErr))
+	assert.Equal(http.StatusBadRequest, statusErr.StatusCode)
+}
@@ -0,0 +1,393 @@
+package apiai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FieldChange is a difference between two versions of an intent or entity.
+// From is nil for added values and To is nil for removed ones.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from,omitempty"`
+	To    interface{} `json:"to,omitempty"`
+}
+
+func (c FieldChange) String() string {
+	switch {
+	case c.From == nil:
+		return fmt.Sprintf("+ %s: %s", c.Field, formatField(c.To))
+	case c.To == nil:
+		return fmt.Sprintf("- %s: %s", c.Field, formatField(c.From))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Field, formatField(c.From), formatField(c.To))
+	}
+}
+
+func formatField(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case fmt.Stringer:
+		return v.String()
+	case bool, int, float64:
+		return fmt.Sprint(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
+
+// Diff renders the plan with the field changes of every update.
+func (p *AgentPlan) Diff() string {
+	symbols := map[PlanAction]string{PlanCreate: "+", PlanUpdate: "~", PlanDelete: "-"}
+	buf := new(bytes.Buffer)
+	for _, c := range p.Changes {
+		fmt.Fprintf(buf, "%s %s %s\n", symbols[c.Action], c.Kind(), c.Name)
+		for _, f := range c.Fields {
+			fmt.Fprintf(buf, "    %s\n", f)
+		}
+	}
+	return buf.String()
+}
+
+// Select returns a plan with only the changes of the named intents and
+// entities, to promote part of the changes.
+func (p *AgentPlan) Select(names ...string) *AgentPlan {
+	selected := map[string]bool{}
+	for _, n := range names {
+		selected[n] = true
+	}
+	return p.Filter(func(c PlanChange) bool { return selected[c.Name] })
+}
+
+func (p *AgentPlan) Filter(keep func(PlanChange) bool) *AgentPlan {
+	filtered := &AgentPlan{}
+	for _, c := range p.Changes {
+		if keep(c) {
+			filtered.Changes = append(filtered.Changes, c)
+		}
+	}
+	return filtered
+}
+
+// DiffIntentFields compares two intents field by field, ignoring ids, counters
+// and the order of training phrases, contexts and events.
+func DiffIntentFields(from, to Intent) []FieldChange {
+	a, b := canonicalIntent(from), canonicalIntent(to)
+	var changes []FieldChange
+	scalar := func(field string, x, y interface{}) {
+		if !reflect.DeepEqual(x, y) {
+			changes = append(changes, FieldChange{field, x, y})
+		}
+	}
+	scalar("name", a.Name, b.Name)
+	scalar("auto", a.Auto, b.Auto)
+	changes = append(changes, diffStringSets("contexts", a.Contexts, b.Contexts)...)
+	changes = append(changes, diffStringSets("userSays", userSaysPhrases(a.UserSays), userSaysPhrases(b.UserSays))...)
+
+	for i := 0; i < len(a.Responses) || i < len(b.Responses); i++ {
+		prefix := ""
+		if i > 0 {
+			prefix = fmt.Sprintf("responses[%d].", i)
+		}
+		if i >= len(a.Responses) || i >= len(b.Responses) {
+			var x, y interface{}
+			if i < len(a.Responses) {
+				x = a.Responses[i]
+			} else {
+				y = b.Responses[i]
+			}
+			changes = append(changes, FieldChange{fmt.Sprintf("responses[%d]", i), x, y})
+			continue
+		}
+		ra, rb := a.Responses[i], b.Responses[i]
+		scalar(prefix+"action", ra.Action, rb.Action)
+		scalar(prefix+"resetContexts", ra.ResetContexts, rb.ResetContexts)
+		changes = append(changes, diffStringSets(prefix+"affectedContexts", contextNames(ra.AffectedContexts), contextNames(rb.AffectedContexts))...)
+		changes = append(changes, diffParams(prefix+"parameters", ra.Params, rb.Params)...)
+		changes = append(changes, diffMessages(prefix+"messages", ra.Messages, rb.Messages)...)
+	}
+
+	scalar("priority", a.Priority, b.Priority)
+	scalar("webhookUsed", a.WebhookUsed, b.WebhookUsed)
+	scalar("webhookForSlotFilling", a.WebhookForSlotFilling, b.WebhookForSlotFilling)
+	scalar("fallbackIntent", a.FallbackIntent, b.FallbackIntent)
+	scalar("cortanaCommand", a.CortanaCommand, b.CortanaCommand)
+	changes = append(changes, diffStringSets("events", eventNames(a.Events), eventNames(b.Events))...)
+	return changes
+}
+
+// DiffEntityFields compares two entities field by field, ignoring ids and the
+// order of entries and synonyms.
+func DiffEntityFields(from, to Entity) []FieldChange {
+	var changes []FieldChange
+	if from.Name != to.Name {
+		changes = append(changes, FieldChange{"name", from.Name, to.Name})
+	}
+	if from.IsEnum != to.IsEnum {
+		changes = append(changes, FieldChange{"isEnum", from.IsEnum, to.IsEnum})
+	}
+	if from.AutomatedExpansion != to.AutomatedExpansion {
+		changes = append(changes, FieldChange{"automatedExpansion", from.AutomatedExpansion, to.AutomatedExpansion})
+	}
+	a, b := canonicalEntity(from), canonicalEntity(to)
+	plan := DiffEntries(a.Entries, b.Entries)
+	for _, e := range plan.Add {
+		changes = append(changes, FieldChange{"entries", nil, e.Value + " (" + strings.Join(e.Synonyms, ", ") + ")"})
+	}
+	for _, c := range plan.Changes {
+		for _, s := range c.AddedSynonyms {
+			changes = append(changes, FieldChange{"entries[" + c.Value + "].synonyms", nil, s})
+		}
+		for _, s := range c.RemovedSynonyms {
+			changes = append(changes, FieldChange{"entries[" + c.Value + "].synonyms", s, nil})
+		}
+	}
+	for _, v := range plan.Delete {
+		changes = append(changes, FieldChange{"entries", v, nil})
+	}
+	return changes
+}
+
+func diffStringSets(field string, from, to []string) []FieldChange {
+	var changes []FieldChange
+	in := func(values []string, v string) bool {
+		i := sort.SearchStrings(values, v)
+		return i < len(values) && values[i] == v
+	}
+	from, to = sortedStrings(from), sortedStrings(to)
+	for _, v := range from {
+		if !in(to, v) {
+			changes = append(changes, FieldChange{field, v, nil})
+		}
+	}
+	for _, v := range to {
+		if !in(from, v) {
+			changes = append(changes, FieldChange{field, nil, v})
+		}
+	}
+	return changes
+}
+
+func diffParams(field string, from, to []IntentParameter) []FieldChange {
+	var changes []FieldChange
+	byName := map[string]IntentParameter{}
+	for _, p := range from {
+		byName[p.Name] = p
+	}
+	seen := map[string]bool{}
+	for _, p := range to {
+		seen[p.Name] = true
+		prev, ok := byName[p.Name]
+		switch {
+		case !ok:
+			changes = append(changes, FieldChange{field, nil, p})
+		case !reflect.DeepEqual(prev, p):
+			changes = append(changes, FieldChange{field + "." + p.Name, prev, p})
+		}
+	}
+	for _, p := range from {
+		if !seen[p.Name] {
+			changes = append(changes, FieldChange{field, p, nil})
+		}
+	}
+	return changes
+}
+
+func userSaysPhrases(userSays []UserSays) []string {
+	phrases := make([]string, len(userSays))
+	for i, us := range userSays {
+		phrases[i] = us.String()
+	}
+	return phrases
+}
+
+func contextNames(contexts []Context) []string {
+	names := make([]string, len(contexts))
+	for i, c := range contexts {
+		names[i] = fmt.Sprintf("%s (lifespan %d)", c.Name, c.Lifespan)
+	}
+	return names
+}
+
+// diffMessages compares two sets of messages as whole values, so cards,
+// replies and payloads changes are reported as well as speech ones.
+func diffMessages(field string, from, to []Message) []FieldChange {
+	var changes []FieldChange
+	a, b := messageValues(from), messageValues(to)
+	in := func(values []json.RawMessage, v json.RawMessage) bool {
+		for _, w := range values {
+			if bytes.Equal(v, w) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, v := range a {
+		if !in(b, v) {
+			changes = append(changes, FieldChange{field, v, nil})
+		}
+	}
+	for _, v := range b {
+		if !in(a, v) {
+			changes = append(changes, FieldChange{field, nil, v})
+		}
+	}
+	return changes
+}
+
+// messageValues renders messages as JSON without their empty fields.
+func messageValues(messages []Message) []json.RawMessage {
+	values := make([]json.RawMessage, 0, len(messages))
+	for _, m := range messages {
+		var fields map[string]interface{}
+		b, _ := json.Marshal(m)
+		json.Unmarshal(b, &fields)
+		for k, v := range fields {
+			switch v := v.(type) {
+			case nil:
+				delete(fields, k)
+			case string:
+				if v == "" {
+					delete(fields, k)
+				}
+			case []interface{}:
+				if len(v) == 0 {
+					delete(fields, k)
+				}
+			}
+		}
+		b, _ = json.Marshal(fields)
+		values = append(values, b)
+	}
+	return values
+}
+
+func eventNames(events []Event) []string {
+	names := make([]string, len(events))
+	for i, e := range events {
+		names[i] = e.Name
+	}
+	return names
+}
+
+// diffAgents returns the changes that turn an agent with the from intents and
+// entities into one with the to intents and entities. Ids are taken from from.
+func diffAgents(fromIntents []Intent, fromEntities []Entity, toIntents []Intent, toEntities []Entity) *AgentPlan {
+	// Creates go first so updated intents and composite entities can
+	// reference new entities, and deletes last once nothing references them.
+	var creates, updates, deletes []PlanChange
+
+	currentEntities := map[string]Entity{}
+	for _, e := range fromEntities {
+		currentEntities[e.Name] = e
+	}
+	wantedEntities := map[string]bool{}
+	var created []Entity
+	for _, e := range toEntities {
+		wantedEntities[e.Name] = true
+		current, ok := currentEntities[e.Name]
+		if !ok {
+			e.Id = ""
+			created = append(created, e)
+			continue
+		}
+		if fields := DiffEntityFields(current, e); len(fields) > 0 {
+			e.Id = current.Id
+			entity := e
+			updates = append(updates, PlanChange{Action: PlanUpdate, Id: current.Id, Name: e.Name, Entity: &entity, Fields: fields})
+		}
+	}
+	// Created entities may reference each other; keep them in dependency order
+	// when possible.
+	if sorted, err := ResolveEntityDependencies(created); err == nil {
+		created = sorted
+	}
+	for i := range created {
+		creates = append(creates, PlanChange{Action: PlanCreate, Name: created[i].Name, Entity: &created[i]})
+	}
+
+	currentIntents := map[string]Intent{}
+	for _, i := range fromIntents {
+		currentIntents[i.Name] = i
+	}
+	wantedIntents := map[string]bool{}
+	for _, i := range toIntents {
+		wantedIntents[i.Name] = true
+		current, ok := currentIntents[i.Name]
+		// Training phrase ids belong to the source agent.
+		intent := withoutUserSaysIds(i)
+		if !ok {
+			intent.Id = ""
+			creates = append(creates, PlanChange{Action: PlanCreate, Name: i.Name, Intent: &intent})
+			continue
+		}
+		intent.Id = current.Id
+		if fields := DiffIntentFields(current, intent); len(fields) > 0 {
+			updates = append(updates, PlanChange{Action: PlanUpdate, Id: current.Id, Name: i.Name, Intent: &intent, Fields: fields})
+		}
+	}
+
+	for _, i := range fromIntents {
+		if !wantedIntents[i.Name] {
+			intent := i
+			deletes = append(deletes, PlanChange{Action: PlanDelete, Id: i.Id, Name: i.Name, Intent: &intent})
+		}
+	}
+	for _, e := range fromEntities {
+		if !wantedEntities[e.Name] {
+			entity := e
+			deletes = append(deletes, PlanChange{Action: PlanDelete, Id: e.Id, Name: e.Name, Entity: &entity})
+		}
+	}
+
+	plan := &AgentPlan{}
+	plan.Changes = append(plan.Changes, creates...)
+	plan.Changes = append(plan.Changes, updates...)
+	plan.Changes = append(plan.Changes, deletes...)
+	return plan
+}
+
+func withoutUserSaysIds(intent Intent) Intent {
+	userSays := make([]UserSays, len(intent.UserSays))
+	for i, us := range intent.UserSays {
+		us.Id = ""
+		userSays[i] = us
+	}
+	intent.UserSays = userSays
+	return intent
+}
+
+// DiffSnapshots returns the changes that turn the agent of from into the one
+// of to. Ids in the plan are the ones of from.
+func DiffSnapshots(from, to *AgentSnapshot) *AgentPlan {
+	return diffAgents(from.Intents, from.Entities, to.Intents, to.Entities)
+}
+
+// PlanPromotion compares the agents of source and target, for instance a
+// staging and a production agent, and returns the changes that would make
+// target match source. Intents and entities only in target are deleted only
+// when prune is set. Apply the plan, or part of it, with target.ApplyAgentPlan.
+func PlanPromotion(source, target *ApiClient, parallelism int, prune bool) (*AgentPlan, error) {
+	from, err := target.Snapshot(parallelism)
+	if err != nil {
+		return nil, err
+	}
+	to, err := source.Snapshot(parallelism)
+	if err != nil {
+		return nil, err
+	}
+	plan := DiffSnapshots(from, to)
+	if !prune {
+		plan = plan.Filter(func(c PlanChange) bool { return c.Action != PlanDelete })
+	}
+	return plan, nil
+}
@@ -0,0 +1,181 @@
+package apiai
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffIntentFields(t *testing.T) {
+	assert := assert.New(t)
+
+	from := Intent{
+		Id:       "i1",
+		Name:     "order",
+		Contexts: []string{"shop"},
+		UserSays: []UserSays{
+			{Id: "u1", Data: []Data{{Text: "a coffee"}}, Count: 2},
+			{Id: "u2", Data: []Data{{Text: "a tea"}}},
+		},
+		Responses: []IntentResponse{{
+			Action:   "order.create",
+			Params:   []IntentParameter{{Name: "drink", DataType: "@drink", Value: "$drink"}},
+			Messages: []Message{{Type: 0, Speech: "Done"}},
+		}},
+		Priority: 500000,
+	}
+	to := Intent{
+		Name:     "order",
+		Contexts: []string{"shop"},
+		UserSays: []UserSays{
+			{Data: []Data{{Text: "a tea"}}},
+			{Data: []Data{{Text: "a "}, {Text: "coffee", Meta: "@drink", Alias: "drink", UserDefined: true}}},
+		},
+		Responses: []IntentResponse{{
+			Action:   "order.create",
+			Params:   []IntentParameter{{Name: "drink", DataType: "@drink", Value: "$drink", Required: true}},
+			Messages: []Message{{Type: "0", Speech: "Done"}},
+		}},
+		Priority: 1000,
+	}
+
+	assert.Equal([]FieldChange{
+		{Field: "userSays", From: "a coffee"},
+		{Field: "userSays", To: "a [coffee](@drink:drink)"},
+		{Field: "parameters.drink",
+			From: IntentParameter{Name: "drink", DataType: "@drink", Value: "$drink", Prompts: []string{}},
+			To:   IntentParameter{Name: "drink", DataType: "@drink", Value: "$drink", Required: true, Prompts: []string{}}},
+		{Field: "priority", From: 500000, To: 1000},
+	}, DiffIntentFields(from, to))
+	assert.True(IntentsEqual(from, from))
+	assert.False(IntentsEqual(from, to))
+}
+
+func TestDiffIntentFieldsMessages(t *testing.T) {
+	assert := assert.New(t)
+
+	card := func(title string) Intent {
+		return Intent{Name: "menu", Responses: []IntentResponse{{
+			Messages: []Message{
+				{Type: 0, Speech: "Our menu"},
+				{Type: 1, Title: title, Buttons: []CardButton{{Text: "Order", Postback: "order"}}},
+			},
+		}}}
+	}
+
+	changes := DiffIntentFields(card("Coffee"), card("Tea"))
+	assert.Len(changes, 2)
+	assert.Equal(`- messages: {"buttons":[{"Postback":"order","Text":"Order"}],"title":"Coffee","type":"1"}`, changes[0].String())
+	assert.Equal(`+ messages: {"buttons":[{"Postback":"order","Text":"Order"}],"title":"Tea","type":"1"}`, changes[1].String())
+	assert.False(IntentsEqual(card("Coffee"), card("Tea")))
+	assert.True(IntentsEqual(card("Coffee"), card("Coffee")))
+}
+
+func TestDiffAgentsOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	from := []Entity{
+		{Id: "e1", Name: "order", IsEnum: true, Entries: []Entry{{Value: "@drink:drink", Synonyms: []string{"@drink:drink"}}}},
+		{Id: "e2", Name: "drink", Entries: []Entry{{Value: "coffee", Synonyms: []string{"coffee"}}}},
+		{Id: "e3", Name: "legacy", Entries: []Entry{{Value: "old", Synonyms: []string{"old"}}}},
+	}
+	to := []Entity{
+		{Name: "order", IsEnum: true, Entries: []Entry{
+			{Value: "@drink:drink", Synonyms: []string{"@drink:drink"}},
+			{Value: "@size:size @drink:drink", Synonyms: []string{"@size:size @drink:drink"}},
+		}},
+		{Name: "drink", Entries: []Entry{{Value: "coffee", Synonyms: []string{"coffee"}}}},
+		{Name: "size", Entries: []Entry{{Value: "large", Synonyms: []string{"large"}}}},
+	}
+
+	plan := diffAgents(nil, from, nil, to)
+	assert.Equal(`+ entity size
+~ entity order
+- entity legacy
+Plan: 1 to create, 1 to update, 1 to delete.
+`, plan.String(), "the composite entity is updated after the entity it references is created")
+}
+
+func TestDiffEntityFields(t *testing.T) {
+	assert := assert.New(t)
+
+	from := Entity{Id: "e1", Name: "drink", Entries: []Entry{
+		{Value: "coffee", Synonyms: []string{"coffee", "java"}},
+		{Value: "tea", Synonyms: []string{"tea"}},
+	}}
+	to := Entity{Name: "drink", AutomatedExpansion: true, Entries: []Entry{
+		{Value: "water", Synonyms: []string{"water"}},
+		{Value: "coffee", Synonyms: []string{"java", "coffee", "espresso"}},
+	}}
+
+	changes := DiffEntityFields(from, to)
+	assert.Equal([]FieldChange{
+		{Field: "automatedExpansion", From: false, To: true},
+		{Field: "entries", To: "water (water)"},
+		{Field: "entries[coffee].synonyms", To: "espresso"},
+		{Field: "entries", From: "tea"},
+	}, changes)
+	assert.Equal(`~ automatedExpansion: false -> true`, changes[0].String())
+	assert.Equal(`+ entries: "water (water)"`, changes[1].String())
+	assert.Equal(`- entries: "tea"`, changes[3].String())
+}
+
+func TestPlanPromotion(t *testing.T) {
+	staging, err := NewClient(&ClientConfig{Token: "stagingToken"})
+	if err != nil {
+		t.FailNow()
+	}
+	production, err := NewClient(&ClientConfig{Token: "productionToken"})
+	if err != nil {
+		t.FailNow()
+	}
+	assert := assert.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	byToken := func(responses map[string]string) httpmock.Responder {
+		return func(req *http.Request) (*http.Response, error) {
+			return httpmock.NewStringResponse(200, responses[req.Header.Get("Authorization")]), nil
+		}
+	}
+	httpmock.RegisterResponder("GET", staging.buildUrl("intents", nil), byToken(map[string]string{
+		"Bearer stagingToken":    `[{"id": "s1", "name": "greet"}, {"id": "s2", "name": "order"}]`,
+		"Bearer productionToken": `[{"id": "p1", "name": "greet"}, {"id": "p3", "name": "legacy"}]`,
+	}))
+	httpmock.RegisterResponder("GET", staging.buildUrl("entities", nil), byToken(map[string]string{
+		"Bearer stagingToken":    `[]`,
+		"Bearer productionToken": `[]`,
+	}))
+	httpmock.RegisterResponder("GET", staging.buildUrl("intents/s1", nil), httpmock.NewStringResponder(200, `{"id": "s1", "name": "greet", "responses": [{"messages": [{"type": 0, "speech": "Hello!"}]}]}`))
+	httpmock.RegisterResponder("GET", staging.buildUrl("intents/s2", nil), httpmock.NewStringResponder(200, `{"id": "s2", "name": "order", "userSays": [{"id": "us-src", "data": [{"text": "a coffee"}]}]}`))
+	httpmock.RegisterResponder("GET", staging.buildUrl("intents/p1", nil), httpmock.NewStringResponder(200, `{"id": "p1", "name": "greet", "responses": [{"messages": [{"type": 0, "speech": "Hi"}]}]}`))
+	httpmock.RegisterResponder("GET", staging.buildUrl("intents/p3", nil), httpmock.NewStringResponder(200, `{"id": "p3", "name": "legacy"}`))
+
+	plan, err := PlanPromotion(staging, production, 2, true)
+	assert.Nil(err)
+	assert.Equal(`+ intent order
+~ intent greet
+    - messages: {"speech":"Hi","type":"0"}
+    + messages: {"speech":"Hello!","type":"0"}
+- intent legacy
+`, plan.Diff())
+	assert.Equal("p1", plan.Changes[1].Id)
+	created := plan.Changes[0].Intent
+	assert.Equal("a coffee", created.UserSays[0].String())
+	assert.Empty(created.UserSays[0].Id, "training phrase ids of the source agent aren't promoted")
+
+	plan, err = PlanPromotion(staging, production, 2, false)
+	assert.Nil(err)
+	plan = plan.Select("greet")
+	assert.Equal("~ intent greet\nPlan: 0 to create, 1 to update, 0 to delete.\n", plan.String())
+
+	var updated string
+	httpmock.RegisterResponder("PUT", production.buildUrl("intents/p1", nil), func(req *http.Request) (*http.Response, error) {
+		updated = req.Header.Get("Authorization")
+		return httpmock.NewStringResponse(200, `{}`), nil
+	})
+	assert.Nil(production.ApplyAgentPlan(plan))
+	assert.Equal("Bearer productionToken", updated)
+}
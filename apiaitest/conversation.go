@@ -0,0 +1,34 @@
+package apiaitest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/marcossegovia/apiai-go"
+)
+
+// AssertConversation plays the conversation against q, a live ApiClient or a
+// FakeAgent, and reports every unmet expectation as a test error. It returns
+// whether the conversation went as scripted.
+func AssertConversation(t testing.TB, q apiai.Querier, c *apiai.Conversation) bool {
+	t.Helper()
+	results, err := c.Play(q)
+	passed := err == nil
+	for i, r := range results {
+		for _, f := range r.Failures {
+			passed = false
+			t.Errorf("conversation %q, turn %d %s: %s", c.Name, i+1, turnLabel(r.Turn), f)
+		}
+	}
+	if err != nil {
+		t.Errorf("conversation %q: %v", c.Name, err)
+	}
+	return passed
+}
+
+func turnLabel(turn apiai.ConversationTurn) string {
+	if turn.Event != "" {
+		return "(event " + turn.Event + ")"
+	}
+	return fmt.Sprintf("%q", turn.Say)
+}
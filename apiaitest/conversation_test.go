@@ -0,0 +1,70 @@
+package apiaitest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/marcossegovia/apiai-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertConversation(t *testing.T) {
+	assert := assert.New(t)
+
+	c, err := apiai.LoadConversation(strings.NewReader(`
+name: order a coffee
+turns:
+  - event: WELCOME
+    speech: "^Hello"
+  - say: I want a coffee
+    intent: order
+    speech: "(?i)which size"
+    contexts: [ordering]
+  - say: large
+    action: order.size
+`))
+	assert.Nil(err)
+	assert.True(AssertConversation(t, fakeCoffeeAgent(), c))
+
+	c.Turns[2].Say = "huge"
+	c.Turns[2].Contexts = []string{"paying"}
+	rt := &recordingT{TB: t}
+	assert.False(AssertConversation(rt, fakeCoffeeAgent(), c))
+	assert.Equal([]string{
+		`conversation "order a coffee", turn 3 "huge": expected action "order.size", got ""`,
+		`conversation "order a coffee", turn 3 "huge": expected context "paying" to be active`,
+	}, rt.errors)
+}
+
+func TestAssertRecordedConversation(t *testing.T) {
+	assert := assert.New(t)
+
+	agent := fakeCoffeeAgent()
+	recorded := &apiai.Conversation{Name: "recorded"}
+	for _, q := range []apiai.Query{
+		{Event: apiai.Event{Name: "WELCOME"}, SessionId: "s1"},
+		{Query: []string{"I want a coffee"}, SessionId: "s1"},
+	} {
+		resp, _ := agent.Query(q)
+		recorded.Turns = append(recorded.Turns, apiai.RecordTurn(q, resp))
+	}
+
+	buf := new(bytes.Buffer)
+	assert.Nil(recorded.Write(buf))
+	loaded, err := apiai.LoadConversation(buf)
+	assert.Nil(err)
+	assert.True(AssertConversation(t, fakeCoffeeAgent(), loaded))
+}
@@ -0,0 +1,170 @@
+// Package apiaitest provides utilities to test api.ai agents and clients
+// without a live agent.
+package apiaitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/marcossegovia/apiai-go"
+)
+
+// FakeAgent answers queries locally from a set of intents, to test clients
+// and conversations without an api.ai agent. Queries match the examples of
+// training phrases, ignoring case and punctuation, and the values of
+// annotated examples become parameters. Templates are not matched. Contexts
+// are tracked per session with their lifespans as api.ai does.
+//
+// FakeAgent is an http.Handler serving the query endpoint, so it can back
+// an apiai.ApiClient with httptest.NewServer and ClientConfig.BaseURL, and
+// it's also an apiai.Querier to use it directly.
+type FakeAgent struct {
+	mu       sync.Mutex
+	intents  []apiai.Intent
+	sessions map[string][]apiai.Context
+}
+
+func NewFakeAgent(intents []apiai.Intent) *FakeAgent {
+	return &FakeAgent{intents: intents, sessions: map[string][]apiai.Context{}}
+}
+
+func (a *FakeAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" || !strings.HasSuffix(r.URL.Path, "/query") {
+		http.NotFound(w, r)
+		return
+	}
+	var q apiai.Query
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp, _ := a.Query(q)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (a *FakeAgent) Query(q apiai.Query) (*apiai.QueryResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	active := a.sessions[q.SessionId]
+	if q.ResetContexts {
+		active = nil
+	}
+	for _, c := range q.Contexts {
+		active = apiai.SetContext(active, c)
+	}
+
+	text := ""
+	if len(q.Query) > 0 {
+		text = q.Query[0]
+	}
+	intent, params := a.match(text, q.Event.Name, active)
+
+	// Active contexts lose one unit of lifespan on every query.
+	active = apiai.DecayContexts(active)
+
+	resp := &apiai.QueryResponse{
+		Id:        fmt.Sprintf("fake-%d", time.Now().UnixNano()),
+		Timestamp: time.Now().UTC(),
+		Language:  q.Language,
+		SessionId: q.SessionId,
+		Status:    apiai.Status{Code: http.StatusOK, ErrorType: "success"},
+		Result: apiai.Result{
+			Source:        "agent",
+			ResolvedQuery: text,
+			Action:        apiai.UnknownInputAction,
+			Params:        params,
+		},
+	}
+	if intent != nil {
+		resp.Result.Score = 1
+		resp.Result.Action = ""
+		resp.Result.Metadata = apiai.Metadata{IntentId: intent.Id, IntentName: intent.Name}
+		if len(intent.Responses) > 0 {
+			r := intent.Responses[0]
+			resp.Result.Action = r.Action
+			if r.ResetContexts {
+				active = nil
+			}
+			for _, c := range r.AffectedContexts {
+				active = apiai.SetContext(active, c)
+			}
+			for _, m := range r.Messages {
+				resp.Result.Fulfillment.Messages = append(resp.Result.Fulfillment.Messages, m)
+				if resp.Result.Fulfillment.Speech == "" {
+					resp.Result.Fulfillment.Speech = m.Speech
+				}
+			}
+		}
+	}
+	a.sessions[q.SessionId] = active
+	resp.Result.Contexts = append([]apiai.Context{}, active...)
+	return resp, nil
+}
+
+// match returns the intent for a query, preferring the ones with more input
+// contexts, or the fallback intent with most input contexts.
+func (a *FakeAgent) match(text, event string, active []apiai.Context) (*apiai.Intent, map[string]interface{}) {
+	var best, fallback *apiai.Intent
+	var bestParams map[string]interface{}
+	query := normalizeUtterance(text)
+	for i := range a.intents {
+		intent := &a.intents[i]
+		if !apiai.ContextsActive(intent.Contexts, active) {
+			continue
+		}
+		if intent.FallbackIntent {
+			if event == "" && (fallback == nil || len(intent.Contexts) > len(fallback.Contexts)) {
+				fallback = intent
+			}
+			continue
+		}
+		if best != nil && len(intent.Contexts) <= len(best.Contexts) {
+			continue
+		}
+		if event != "" {
+			for _, e := range intent.Events {
+				if strings.EqualFold(e.Name, event) {
+					best, bestParams = intent, map[string]interface{}{}
+				}
+			}
+			continue
+		}
+		for _, us := range intent.UserSays {
+			if us.IsTemplate {
+				continue
+			}
+			var b strings.Builder
+			params := map[string]interface{}{}
+			for _, d := range us.Data {
+				b.WriteString(d.Text)
+				if d.Alias != "" {
+					params[d.Alias] = d.Text
+				}
+			}
+			if normalizeUtterance(b.String()) == query {
+				best, bestParams = intent, params
+				break
+			}
+		}
+	}
+	if best != nil {
+		return best, bestParams
+	}
+	return fallback, map[string]interface{}{}
+}
+
+func normalizeUtterance(s string) string {
+	return apiai.NormalizeSynonym(strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) && r != '-' && r != '_' && r != '\'' {
+			return ' '
+		}
+		return r
+	}, s))
+}
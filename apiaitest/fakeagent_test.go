@@ -0,0 +1,78 @@
+package apiaitest
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/marcossegovia/apiai-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeCoffeeAgent() *FakeAgent {
+	order, _ := apiai.ParseUserSays("I want a [coffee](@drink:drink)")
+	size, _ := apiai.ParseUserSays("[large](@size:size)")
+	return NewFakeAgent([]apiai.Intent{
+		{Name: "welcome", Events: []apiai.Event{{Name: "WELCOME"}}, Responses: []apiai.IntentResponse{{
+			Action:   "input.welcome",
+			Messages: []apiai.Message{{Type: 0, Speech: "Hello! What would you like?"}},
+		}}},
+		{Name: "order", UserSays: []apiai.UserSays{order}, Responses: []apiai.IntentResponse{{
+			Action:           "order.create",
+			AffectedContexts: []apiai.Context{{Name: "ordering", Lifespan: 2}},
+			Messages:         []apiai.Message{{Type: 0, Speech: "Which size?"}},
+		}}},
+		{Name: "order.size", Contexts: []string{"ordering"}, UserSays: []apiai.UserSays{size}, Responses: []apiai.IntentResponse{{
+			Action:        "order.size",
+			ResetContexts: true,
+			Messages:      []apiai.Message{{Type: 0, Speech: "Done"}},
+		}}},
+		{Name: "fallback", FallbackIntent: true, Responses: []apiai.IntentResponse{{
+			Action:   "input.unknown",
+			Messages: []apiai.Message{{Type: 0, Speech: "Sorry?"}},
+		}}},
+		{Name: "order.fallback", FallbackIntent: true, Contexts: []string{"ordering"}, Responses: []apiai.IntentResponse{{
+			Messages: []apiai.Message{{Type: 0, Speech: "Small, medium or large?"}},
+		}}},
+	})
+}
+
+func TestFakeAgent(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(fakeCoffeeAgent())
+	defer server.Close()
+	c, err := apiai.NewClient(&apiai.ClientConfig{Token: "fakeToken", BaseURL: server.URL})
+	if err != nil {
+		t.FailNow()
+	}
+
+	resp, err := c.Query(apiai.Query{Query: []string{"i want a COFFEE!"}, SessionId: "s1"})
+	assert.Nil(err)
+	assert.Equal("order", resp.Result.Metadata.IntentName)
+	assert.Equal(map[string]interface{}{"drink": "coffee"}, resp.Result.Params)
+	assert.Equal([]apiai.Context{{Name: "ordering", Lifespan: 2}}, resp.Result.Contexts)
+
+	resp, err = c.Query(apiai.Query{Query: []string{"hmm"}, SessionId: "s1"})
+	assert.Nil(err)
+	assert.Equal("order.fallback", resp.Result.Metadata.IntentName)
+	assert.Equal([]apiai.Context{{Name: "ordering", Lifespan: 1}}, resp.Result.Contexts)
+
+	resp, err = c.Query(apiai.Query{Query: []string{"large"}, SessionId: "s2"})
+	assert.Nil(err)
+	assert.Equal("fallback", resp.Result.Metadata.IntentName, "contexts are kept per session")
+
+	resp, err = c.Query(apiai.Query{Query: []string{"large"}, SessionId: "s1"})
+	assert.Nil(err)
+	assert.Equal("order.size", resp.Result.Metadata.IntentName)
+	assert.Equal("Done", resp.Result.Fulfillment.Speech)
+	assert.Empty(resp.Result.Contexts)
+
+	resp, err = c.Query(apiai.Query{Event: apiai.Event{Name: "welcome"}, SessionId: "s3"})
+	assert.Nil(err)
+	assert.Equal("input.welcome", resp.Result.Action)
+	assert.Equal(1.0, resp.Result.Score)
+
+	resp, err = NewFakeAgent(nil).Query(apiai.Query{Query: []string{"hi"}})
+	assert.Nil(err)
+	assert.Equal(apiai.UnknownInputAction, resp.Result.Action)
+	assert.Equal(0.0, resp.Result.Score)
+}
@@ -0,0 +1,246 @@
+package apiai
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	agentFile       = "agent.json"
+	packageFile     = "package.json"
+	intentsDir      = "intents/"
+	entitiesDir     = "entities/"
+	userSaysSuffix  = "_usersays_"
+	entriesSuffix   = "_entries_"
+	archiveLanguage = "language"
+)
+
+// AgentArchive is the content of an agent exported as a ZIP file from the
+// api.ai console. Training phrases and entries are stored in per-language
+// files, only the ones in Language are read and written.
+type AgentArchive struct {
+	Agent    map[string]interface{}
+	Language string
+	Intents  []Intent
+	Entities []Entity
+}
+
+func ReadAgentArchiveFile(name string) (*AgentArchive, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return ReadAgentArchive(f, info.Size())
+}
+
+func ReadAgentArchive(r io.ReaderAt, size int64) (*AgentArchive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("apiai: invalid agent archive, %v", err)
+	}
+	// Some exports nest everything in a directory named after the agent.
+	root := ""
+	for _, f := range zr.File {
+		if path.Base(f.Name) == agentFile {
+			root = strings.TrimSuffix(f.Name, agentFile)
+			break
+		}
+	}
+
+	a := &AgentArchive{Language: defaultQueryLang}
+	byName := map[string]*zip.File{}
+	for _, f := range zr.File {
+		byName[strings.TrimPrefix(f.Name, root)] = f
+	}
+	if f, ok := byName[agentFile]; ok {
+		if err := readArchiveJSON(f, &a.Agent); err != nil {
+			return nil, err
+		}
+		if lang, ok := a.Agent[archiveLanguage].(string); ok && lang != "" {
+			a.Language = lang
+		}
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := byName[name]
+		dir, base := path.Split(name)
+		if !strings.HasSuffix(base, ".json") {
+			continue
+		}
+		stem := strings.TrimSuffix(base, ".json")
+		switch dir {
+		case intentsDir:
+			if strings.Contains(stem, userSaysSuffix) {
+				continue
+			}
+			var intent Intent
+			if err := readArchiveJSON(f, &intent); err != nil {
+				return nil, err
+			}
+			if us, ok := byName[intentsDir+stem+userSaysSuffix+a.Language+".json"]; ok {
+				if err := readArchiveJSON(us, &intent.UserSays); err != nil {
+					return nil, err
+				}
+			}
+			a.Intents = append(a.Intents, intent)
+		case entitiesDir:
+			if strings.Contains(stem, entriesSuffix) {
+				continue
+			}
+			var entity Entity
+			if err := readArchiveJSON(f, &entity); err != nil {
+				return nil, err
+			}
+			if entries, ok := byName[entitiesDir+stem+entriesSuffix+a.Language+".json"]; ok {
+				if err := readArchiveJSON(entries, &entity.Entries); err != nil {
+					return nil, err
+				}
+			}
+			a.Entities = append(a.Entities, entity)
+		}
+	}
+	return a, nil
+}
+
+func readArchiveJSON(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := json.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("apiai: invalid %s in agent archive, %v", f.Name, err)
+	}
+	return nil
+}
+
+// WriteFile writes the archive to a ZIP file that can be imported from the
+// api.ai console.
+func (a *AgentArchive) WriteFile(name string) error {
+	buf := new(bytes.Buffer)
+	if err := a.Write(buf); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, buf.Bytes(), 0644)
+}
+
+// Write writes the archive as a ZIP. Files are written sorted and with
+// indented JSON so that exports of the same agent are byte for byte equal.
+// Intents or entities whose names map to the same file name are an error.
+func (a *AgentArchive) Write(w io.Writer) error {
+	lang := a.Language
+	if lang == "" {
+		lang = defaultQueryLang
+	}
+	agent := map[string]interface{}{}
+	for k, v := range a.Agent {
+		agent[k] = v
+	}
+	agent[archiveLanguage] = lang
+
+	files := map[string]interface{}{
+		agentFile:   agent,
+		packageFile: map[string]string{"version": "1.0.0"},
+	}
+	owners := map[string]string{}
+	fileName := func(dir, name string) (string, error) {
+		file := archiveFileName(name)
+		if other, ok := owners[dir+file]; ok {
+			return "", fmt.Errorf("apiai: %q and %q have the same file name %s%s.json in the agent archive", other, name, dir, file)
+		}
+		owners[dir+file] = name
+		return file, nil
+	}
+	for _, intent := range a.Intents {
+		name, err := fileName(intentsDir, intent.Name)
+		if err != nil {
+			return err
+		}
+		userSays := intent.UserSays
+		intent.UserSays = nil
+		body, err := withoutKey(intent, "userSays")
+		if err != nil {
+			return err
+		}
+		files[intentsDir+name+".json"] = body
+		if len(userSays) > 0 {
+			files[intentsDir+name+userSaysSuffix+lang+".json"] = userSays
+		}
+	}
+	for _, entity := range a.Entities {
+		name, err := fileName(entitiesDir, entity.Name)
+		if err != nil {
+			return err
+		}
+		body, err := withoutKey(entity, "entries")
+		if err != nil {
+			return err
+		}
+		files[entitiesDir+name+".json"] = body
+		files[entitiesDir+name+entriesSuffix+lang+".json"] = entity.Entries
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		b, err := json.MarshalIndent(files[name], "", "  ")
+		if err != nil {
+			return err
+		}
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(b); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func withoutKey(v interface{}, key string) (map[string]interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	delete(m, key)
+	return m, nil
+}
+
+func archiveFileName(name string) string {
+	return strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(name)
+}
+
+// DiffAgentArchives returns the changes that turn the agent in from into the
+// one in to, matching intents and entities by name. Ids in the plan are the
+// ones of from, so it can be applied to the agent from was exported from.
+func DiffAgentArchives(from, to *AgentArchive) *AgentPlan {
+	return diffAgents(from.Intents, from.Entities, to.Intents, to.Entities)
+}
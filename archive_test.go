@@ -0,0 +1,121 @@
+package apiai
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testArchive() *AgentArchive {
+	return &AgentArchive{
+		Agent:    map[string]interface{}{"description": "Coffee shop"},
+		Language: "en",
+		Intents: []Intent{
+			{
+				Id:       "i1",
+				Name:     "order",
+				Auto:     true,
+				Contexts: []string{},
+				UserSays: []UserSays{
+					{Id: "u1", Data: []Data{{Text: "a "}, {Text: "coffee", Meta: "@drink", Alias: "drink", UserDefined: true}}},
+				},
+				Responses: []IntentResponse{{Action: "order.create"}},
+				Priority:  500000,
+			},
+		},
+		Entities: []Entity{
+			{Id: "e1", Name: "drink", Entries: []Entry{{Value: "coffee", Synonyms: []string{"coffee", "java"}}}},
+		},
+	}
+}
+
+func TestAgentArchive(t *testing.T) {
+	assert := assert.New(t)
+	archive := testArchive()
+
+	buf := new(bytes.Buffer)
+	assert.Nil(archive.Write(buf))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(err)
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal([]string{
+		"agent.json",
+		"entities/drink.json",
+		"entities/drink_entries_en.json",
+		"intents/order.json",
+		"intents/order_usersays_en.json",
+		"package.json",
+	}, names)
+
+	read, err := ReadAgentArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(err)
+	assert.Equal("en", read.Language)
+	assert.Equal("Coffee shop", read.Agent["description"])
+	assert.Equal(archive.Entities, read.Entities)
+	assert.Equal(archive.Intents[0].UserSays, read.Intents[0].UserSays)
+	assert.True(IntentsEqual(archive.Intents[0], read.Intents[0]))
+
+	again := new(bytes.Buffer)
+	assert.Nil(read.Write(again))
+	assert.Equal(buf.Bytes(), again.Bytes(), "exports are deterministic")
+}
+
+func TestAgentArchiveFileNameCollision(t *testing.T) {
+	archive := testArchive()
+	archive.Intents = append(archive.Intents, Intent{Name: "a/b"}, Intent{Name: "a_b"})
+
+	err := archive.Write(new(bytes.Buffer))
+	assert.EqualError(t, err, `apiai: "a/b" and "a_b" have the same file name intents/a_b.json in the agent archive`)
+}
+
+func TestReadAgentArchiveNested(t *testing.T) {
+	assert := assert.New(t)
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for name, body := range map[string]string{
+		"coffee/agent.json":                     `{"language": "es"}`,
+		"coffee/intents/pedir.json":             `{"name": "pedir"}`,
+		"coffee/intents/pedir_usersays_es.json": `[{"data": [{"text": "un café"}]}]`,
+		"coffee/intents/pedir_usersays_en.json": `[{"data": [{"text": "a coffee"}]}]`,
+	} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(body))
+	}
+	zw.Close()
+
+	read, err := ReadAgentArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(err)
+	assert.Equal("es", read.Language)
+	assert.Equal([]Intent{{Name: "pedir", UserSays: []UserSays{{Data: []Data{{Text: "un café"}}}}}}, read.Intents)
+
+	_, err = ReadAgentArchive(bytes.NewReader([]byte("nope")), 4)
+	assert.NotNil(err)
+}
+
+func TestDiffAgentArchives(t *testing.T) {
+	assert := assert.New(t)
+	from := testArchive()
+	to := testArchive()
+
+	assert.True(DiffAgentArchives(from, to).Empty())
+
+	to.Entities[0].Entries[0].Synonyms = []string{"coffee"}
+	to.Entities = append(to.Entities, Entity{Name: "size", Entries: []Entry{{Value: "large", Synonyms: []string{"large"}}}})
+	to.Intents = []Intent{{Name: "greet", Responses: []IntentResponse{{Action: "greet"}}}}
+
+	plan := DiffAgentArchives(from, to)
+	assert.Equal(`+ entity size
++ intent greet
+~ entity drink
+- intent order
+Plan: 2 to create, 1 to update, 1 to delete.
+`, plan.String())
+	assert.Equal("e1", plan.Changes[2].Id)
+	assert.Equal("i1", plan.Changes[3].Id)
+}
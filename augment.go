@@ -0,0 +1,215 @@
+package apiai
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultMaxPerTemplate = 20
+	defaultMaxPerIntent   = 200
+)
+
+// defaultSamples are example values for common system entities, which have no
+// entries to expand templates with.
+var defaultSamples = map[string][]string{
+	"sys.number":         {"1", "2", "5", "10"},
+	"sys.number-integer": {"1", "3", "12"},
+	"sys.date":           {"today", "tomorrow", "next monday"},
+	"sys.time":           {"5pm", "noon", "8:30 am"},
+	"sys.any":            {"something"},
+	"sys.given-name":     {"John", "Maria"},
+	"sys.geo-city":       {"London", "Madrid", "New York"},
+	"sys.color":          {"red", "blue"},
+}
+
+// Augmenter generates annotated examples from template training phrases,
+// substituting entity references with entity values and synonyms. An
+// Augmenter isn't safe for concurrent use.
+type Augmenter struct {
+	// Entities by name, used for the values of references.
+	Entities map[string]Entity
+	// Samples are values for entities without entries, like system entities.
+	Samples map[string][]string
+	// Synonyms substitutes synonyms as well as entry values.
+	Synonyms bool
+	// MaxPerTemplate and MaxPerIntent cap the examples generated for a
+	// template and the examples added to an intent.
+	MaxPerTemplate int
+	MaxPerIntent   int
+}
+
+func NewAugmenter(entities []Entity) *Augmenter {
+	a := &Augmenter{
+		Entities:       map[string]Entity{},
+		Samples:        map[string][]string{},
+		Synonyms:       true,
+		MaxPerTemplate: defaultMaxPerTemplate,
+		MaxPerIntent:   defaultMaxPerIntent,
+	}
+	for _, e := range entities {
+		a.Entities[e.Name] = e
+	}
+	for name, values := range defaultSamples {
+		a.Samples[name] = values
+	}
+	return a
+}
+
+// values returns the distinct values an entity reference can be replaced
+// with. Entries referencing other entities are skipped.
+func (a *Augmenter) values(entity string) []string {
+	e, ok := a.Entities[entity]
+	if !ok || len(e.Entries) == 0 {
+		return a.Samples[entity]
+	}
+	var values []string
+	seen := map[string]bool{}
+	add := func(v string) {
+		if v == "" || strings.Contains(v, "@") || seen[NormalizeSynonym(v)] {
+			return
+		}
+		seen[NormalizeSynonym(v)] = true
+		values = append(values, v)
+	}
+	for _, entry := range e.Entries {
+		add(entry.Value)
+		if a.Synonyms {
+			for _, s := range entry.Synonyms {
+				add(s)
+			}
+		}
+	}
+	return values
+}
+
+// Expand returns the examples generated from a template phrase, at most
+// MaxPerTemplate different combinations of values. Every slot rotates through
+// its values from one example to the next, so capped expansions still use
+// every value of the slots with fewer values than examples. It returns nil for
+// examples and for templates referencing entities without values.
+func (a *Augmenter) Expand(us UserSays) []UserSays {
+	if !us.IsTemplate {
+		return nil
+	}
+	var slots []int
+	var choices [][]string
+	for i, d := range us.Data {
+		if d.Meta == "" {
+			continue
+		}
+		values := a.values(strings.TrimPrefix(d.Meta, "@"))
+		if len(values) == 0 {
+			return nil
+		}
+		slots = append(slots, i)
+		choices = append(choices, values)
+	}
+	limit := a.MaxPerTemplate
+	if limit <= 0 {
+		limit = math.MaxInt32
+	}
+	// Stop multiplying at the limit so large entities can't overflow it.
+	n := 1
+	for _, values := range choices {
+		if n > limit/len(values) {
+			n = limit
+			break
+		}
+		n *= len(values)
+	}
+	if n > limit {
+		n = limit
+	}
+
+	// Combination k is written in mixed radix, slots with more values first,
+	// and each slot takes the sum of its digit and the previous ones. The
+	// first combinations turn every slot at once and all of them differ.
+	order := make([]int, len(slots))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return len(choices[order[i]]) > len(choices[order[j]]) })
+
+	examples := make([]UserSays, 0, n)
+	for k := 0; k < n; k++ {
+		data := make([]Data, len(us.Data))
+		copy(data, us.Data)
+		rest, sum := k, 0
+		for _, s := range order {
+			values := choices[s]
+			sum += rest % len(values)
+			rest /= len(values)
+			d := &data[slots[s]]
+			d.Text = values[sum%len(values)]
+			d.UserDefined = true
+		}
+		examples = append(examples, UserSays{Data: data})
+	}
+	return examples
+}
+
+// AugmentIntent returns the intent with the examples generated from its
+// templates added, without duplicating existing examples, and the number of
+// examples added.
+func (a *Augmenter) AugmentIntent(intent Intent) (Intent, int) {
+	seen := map[string]bool{}
+	for _, us := range intent.UserSays {
+		if !us.IsTemplate {
+			seen[NormalizeSynonym(us.String())] = true
+		}
+	}
+	userSays := append([]UserSays{}, intent.UserSays...)
+	added := 0
+	for _, us := range intent.UserSays {
+		for _, example := range a.Expand(us) {
+			if a.MaxPerIntent > 0 && added >= a.MaxPerIntent {
+				break
+			}
+			key := NormalizeSynonym(example.String())
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			userSays = append(userSays, example)
+			added++
+		}
+	}
+	intent.UserSays = userSays
+	return intent, added
+}
+
+// AugmentIntent adds examples generated from the templates of an intent and
+// updates it. Entities referenced by the templates and unknown to a are
+// fetched with GetEntity. It returns the number of examples added.
+func (c *ApiClient) AugmentIntent(id string, a *Augmenter) (int, error) {
+	intent, err := c.GetIntent(id)
+	if err != nil {
+		return 0, err
+	}
+	for _, us := range intent.UserSays {
+		if !us.IsTemplate {
+			continue
+		}
+		for _, d := range us.Data {
+			name := strings.TrimPrefix(d.Meta, "@")
+			if name == "" || strings.HasPrefix(name, systemEntityPrefix) {
+				continue
+			}
+			if _, ok := a.Entities[name]; ok {
+				continue
+			}
+			entity, err := c.GetEntity(name)
+			if err != nil {
+				return 0, err
+			}
+			a.Entities[name] = *entity
+		}
+	}
+	augmented, added := a.AugmentIntent(*intent)
+	if added == 0 {
+		return 0, nil
+	}
+	return added, c.UpdateIntent(id, augmented)
+}
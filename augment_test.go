@@ -0,0 +1,127 @@
+package apiai
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAugmenterExpand(t *testing.T) {
+	assert := assert.New(t)
+
+	a := NewAugmenter([]Entity{
+		{Name: "drink", Entries: []Entry{
+			{Value: "coffee", Synonyms: []string{"coffee", "java"}},
+			{Value: "tea", Synonyms: []string{"Tea"}},
+		}},
+		{Name: "order", Entries: []Entry{{Value: "@drink:drink", Synonyms: []string{"@drink:drink"}}}},
+	})
+	template, _ := ParseUserSays("@sys.number:count @drink please")
+
+	phrases := func(userSays []UserSays) []string {
+		var p []string
+		for _, us := range userSays {
+			p = append(p, us.String())
+		}
+		return p
+	}
+	examples := phrases(a.Expand(template))
+	assert.Len(examples, 12)
+	assert.Len(uniqueStrings(examples), 12, "every combination is generated once")
+	assert.Equal([]string{
+		"[1](@sys.number:count) [coffee](@drink:drink) please",
+		"[2](@sys.number:count) [java](@drink:drink) please",
+		"[5](@sys.number:count) [tea](@drink:drink) please",
+	}, examples[:3])
+
+	a.MaxPerTemplate = 4
+	a.Synonyms = false
+	assert.Equal([]string{
+		"[1](@sys.number:count) [coffee](@drink:drink) please",
+		"[2](@sys.number:count) [tea](@drink:drink) please",
+		"[5](@sys.number:count) [coffee](@drink:drink) please",
+		"[10](@sys.number:count) [tea](@drink:drink) please",
+	}, phrases(a.Expand(template)), "capped expansions use every value of every slot")
+
+	a.MaxPerTemplate = 3
+	cups, _ := ParseUserSays("@drink @cup @drink")
+	a.Entities["cup"] = Entity{Name: "cup", Entries: []Entry{{Value: "small"}, {Value: "medium"}, {Value: "large"}}}
+	seen := map[int]map[string]bool{0: {}, 2: {}, 4: {}}
+	for _, us := range a.Expand(cups) {
+		for i := range seen {
+			seen[i][us.Data[i].Text] = true
+		}
+	}
+	assert.Len(seen[0], 2)
+	assert.Len(seen[2], 3)
+	assert.Len(seen[4], 2)
+
+	a.MaxPerTemplate = 5
+	var huge UserSays
+	values := make([]Entry, 1000)
+	for i := range values {
+		values[i] = Entry{Value: fmt.Sprint(i)}
+	}
+	a.Entities["huge"] = Entity{Name: "huge", Entries: values}
+	for i := 0; i < 8; i++ {
+		huge.Data = append(huge.Data, Data{Text: "@huge:huge", Meta: "@huge"}, Data{Text: " "})
+	}
+	huge.IsTemplate = true
+	assert.Len(a.Expand(huge), 5, "the number of combinations doesn't overflow")
+
+	example, _ := ParseUserSays("a [tea](@drink:drink)")
+	assert.Nil(a.Expand(example))
+	unknown, _ := ParseUserSays("@size please")
+	assert.Nil(a.Expand(unknown))
+	composite, _ := ParseUserSays("@order")
+	assert.Nil(a.Expand(composite), "entries referencing entities are skipped")
+
+	existing, _ := ParseUserSays("a [coffee](@drink:drink)")
+	drinkTemplate, _ := ParseUserSays("a @drink")
+	a.MaxPerIntent = 1
+	intent, added := a.AugmentIntent(Intent{Name: "order", UserSays: []UserSays{existing, drinkTemplate}})
+	assert.Equal(1, added)
+	assert.Equal([]string{"a [coffee](@drink:drink)", "a @drink:drink", "a [tea](@drink:drink)"}, phrases(intent.UserSays))
+	assert.True(intent.UserSays[2].Data[1].UserDefined)
+}
+
+func TestApiClientAugmentIntent(t *testing.T) {
+	c, err := NewClient(&ClientConfig{Token: "fakeToken"})
+	if err != nil {
+		t.FailNow()
+	}
+	assert := assert.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", c.buildUrl("intents/i1", nil), httpmock.NewStringResponder(200, `{
+  "id": "i1",
+  "name": "order",
+  "userSays": [{"isTemplate": true, "data": [{"text": "a "}, {"text": "@drink:drink", "meta": "@drink", "alias": "drink"}]}]
+}`))
+	httpmock.RegisterResponder("GET", c.buildUrl("entities/drink", nil), httpmock.NewStringResponder(200, `{
+  "name": "drink",
+  "entries": [{"value": "coffee", "synonyms": ["coffee"]}, {"value": "tea", "synonyms": ["tea"]}]
+}`))
+	var updated Intent
+	httpmock.RegisterResponder("PUT", c.buildUrl("intents/i1", nil), func(req *http.Request) (*http.Response, error) {
+		b, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(b, &updated)
+		return httpmock.NewStringResponse(200, `{}`), nil
+	})
+
+	added, err := c.AugmentIntent("i1", NewAugmenter(nil))
+	assert.Nil(err)
+	assert.Equal(2, added)
+	assert.Len(updated.UserSays, 3)
+	assert.Equal(Data{Text: "tea", Meta: "@drink", Alias: "drink", UserDefined: true}, updated.UserSays[2].Data[1])
+
+	httpmock.RegisterResponder("GET", c.buildUrl("entities/drink", nil), httpmock.NewStringResponder(404, `{}`))
+	_, err = c.AugmentIntent("i1", NewAugmenter(nil))
+	assert.EqualError(err, "apiai: wops something happens because status code is 404")
+}
@@ -0,0 +1,221 @@
+package apiai
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultBatchSize = 500
+
+type BulkOptions struct {
+	BatchSize   int //Entries per request, defaults to 500
+	Parallelism int //Concurrent requests, defaults to 1
+	Retries     int //Extra attempts for a failing chunk
+	RetryDelay  time.Duration
+	// Progress is called after every uploaded chunk.
+	Progress func(BulkProgress)
+	// Checkpoint resumes a previous upload, skipping its completed chunks.
+	// It is updated as chunks complete and passed to OnCheckpoint so it can
+	// be persisted.
+	Checkpoint   *BulkCheckpoint
+	OnCheckpoint func(BulkCheckpoint)
+}
+
+type BulkProgress struct {
+	Chunk    int
+	Chunks   int
+	Uploaded int
+	Total    int
+	Retries  int
+}
+
+type BulkCheckpoint struct {
+	BatchSize int   `json:"batchSize"`
+	Total     int   `json:"total"`
+	Done      []int `json:"done"`
+}
+
+func (cp *BulkCheckpoint) done(chunk int) bool {
+	for _, d := range cp.Done {
+		if d == chunk {
+			return true
+		}
+	}
+	return false
+}
+
+type BulkError struct {
+	Chunk int
+	Err   error
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("apiai: chunk %d failed, %v", e.Chunk, e.Err)
+}
+
+func (e *BulkError) Unwrap() error {
+	return e.Err
+}
+
+// AddEntriesChunked adds entries splitting them in several AddEntries requests.
+func (c *ApiClient) AddEntriesChunked(idOrName string, entries []Entry, opts BulkOptions) error {
+	return uploadChunks(entries, opts, func(chunk, attempt int, entries []Entry) error {
+		if attempt > 0 {
+			c.metrics().ObserveRetry("entities/:id/entries")
+		}
+		return c.AddEntries(idOrName, entries)
+	})
+}
+
+// UpdateEntriesChunked updates entries splitting them in several UpdateEntries requests.
+func (c *ApiClient) UpdateEntriesChunked(idOrName string, entries []Entry, opts BulkOptions) error {
+	return uploadChunks(entries, opts, func(chunk, attempt int, entries []Entry) error {
+		if attempt > 0 {
+			c.metrics().ObserveRetry("entities/:id/entries")
+		}
+		return c.UpdateEntries(idOrName, entries)
+	})
+}
+
+// UpdateEntityChunked replaces the entity sending its first chunk of entries
+// with UpdateEntity, and adds the rest with AddEntries.
+func (c *ApiClient) UpdateEntityChunked(idOrName string, entity Entity, opts BulkOptions) error {
+	if len(entity.Entries) == 0 {
+		// Without entries there are no chunks, but the entity is still updated
+		// to clear its entries and change its settings.
+		entity.Entries = []Entry{}
+		return c.UpdateEntity(idOrName, entity)
+	}
+	return uploadChunks(entity.Entries, opts, func(chunk, attempt int, entries []Entry) error {
+		if chunk == 0 {
+			if attempt > 0 {
+				c.metrics().ObserveRetry("entities/:id")
+			}
+			e := entity
+			e.Entries = entries
+			return c.UpdateEntity(idOrName, e)
+		}
+		if attempt > 0 {
+			c.metrics().ObserveRetry("entities/:id/entries")
+		}
+		return c.AddEntries(idOrName, entries)
+	})
+}
+
+func uploadChunks(entries []Entry, opts BulkOptions, upload func(chunk, attempt int, entries []Entry) error) error {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.Parallelism <= 0 {
+		opts.Parallelism = 1
+	}
+	cp := opts.Checkpoint
+	if cp == nil {
+		cp = &BulkCheckpoint{}
+	}
+	if len(cp.Done) == 0 {
+		cp.BatchSize, cp.Total = opts.BatchSize, len(entries)
+	}
+	if cp.BatchSize != opts.BatchSize || cp.Total != len(entries) {
+		return fmt.Errorf("apiai: checkpoint of %d entries in batches of %d doesn't match the upload", cp.Total, cp.BatchSize)
+	}
+
+	chunks := (len(entries) + opts.BatchSize - 1) / opts.BatchSize
+	var pending []int
+	for i := 0; i < chunks; i++ {
+		if !cp.done(i) {
+			pending = append(pending, i)
+		}
+	}
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	// The first chunk goes alone so uploads that replace the existing entries,
+	// like UpdateEntityChunked, happen before any other chunk is added.
+	if len(pending) > 0 && pending[0] == 0 {
+		if err := uploadChunk(entries, 0, chunks, opts, cp, &mu, upload); err != nil {
+			return err
+		}
+		pending = pending[1:]
+	}
+	work := make(chan int)
+	for w := 0; w < opts.Parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range work {
+				mu.Lock()
+				failed := firstErr != nil
+				mu.Unlock()
+				if failed {
+					continue
+				}
+				if err := uploadChunk(entries, chunk, chunks, opts, cp, &mu, upload); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, chunk := range pending {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		work <- chunk
+	}
+	close(work)
+	wg.Wait()
+	return firstErr
+}
+
+func uploadChunk(entries []Entry, chunk, chunks int, opts BulkOptions, cp *BulkCheckpoint, mu *sync.Mutex, upload func(chunk, attempt int, entries []Entry) error) error {
+	start := chunk * opts.BatchSize
+	end := start + opts.BatchSize
+	if end > len(entries) {
+		end = len(entries)
+	}
+
+	var err error
+	attempt := 0
+	for ; attempt <= opts.Retries; attempt++ {
+		if attempt > 0 && opts.RetryDelay > 0 {
+			time.Sleep(opts.RetryDelay * time.Duration(attempt))
+		}
+		if err = upload(chunk, attempt, entries[start:end]); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return &BulkError{chunk, err}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	cp.Done = append(cp.Done, chunk)
+	sort.Ints(cp.Done)
+	if opts.OnCheckpoint != nil {
+		opts.OnCheckpoint(BulkCheckpoint{cp.BatchSize, cp.Total, append([]int(nil), cp.Done...)})
+	}
+	if opts.Progress != nil {
+		uploaded := 0
+		for _, d := range cp.Done {
+			if n := len(entries) - d*opts.BatchSize; n < opts.BatchSize {
+				uploaded += n
+			} else {
+				uploaded += opts.BatchSize
+			}
+		}
+		opts.Progress(BulkProgress{Chunk: chunk, Chunks: chunks, Uploaded: uploaded, Total: len(entries), Retries: attempt})
+	}
+	return nil
+}
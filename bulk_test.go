@@ -0,0 +1,149 @@
+package apiai
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func bulkEntries(n int) []Entry {
+	entries := make([]Entry, n)
+	for i := range entries {
+		v := fmt.Sprintf("product-%d", i)
+		entries[i] = Entry{Value: v, Synonyms: []string{v}}
+	}
+	return entries
+}
+
+func TestAddEntriesChunked(t *testing.T) {
+	c, err := NewClient(&ClientConfig{Token: "fakeToken"})
+	if err != nil {
+		t.FailNow()
+	}
+	assert := assert.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var mu sync.Mutex
+	received := map[string]bool{}
+	failures := map[string]int{"product-20": 1}
+	httpmock.RegisterResponder("POST", c.buildUrl("entities/Products/entries", nil), func(req *http.Request) (*http.Response, error) {
+		b, _ := ioutil.ReadAll(req.Body)
+		var entries []Entry
+		json.Unmarshal(b, &entries)
+		mu.Lock()
+		defer mu.Unlock()
+		if failures[entries[0].Value] > 0 {
+			failures[entries[0].Value]--
+			return httpmock.NewStringResponse(http.StatusInternalServerError, `{}`), nil
+		}
+		for _, e := range entries {
+			received[e.Value] = true
+		}
+		return httpmock.NewStringResponse(200, `{}`), nil
+	})
+
+	var progress []BulkProgress
+	var checkpoints []BulkCheckpoint
+	err = c.AddEntriesChunked("Products", bulkEntries(25), BulkOptions{
+		BatchSize:    10,
+		Parallelism:  2,
+		Retries:      1,
+		Progress:     func(p BulkProgress) { progress = append(progress, p) },
+		OnCheckpoint: func(cp BulkCheckpoint) { checkpoints = append(checkpoints, cp) },
+	})
+
+	assert.Nil(err)
+	assert.Len(received, 25)
+	assert.Len(progress, 3)
+	assert.Equal(BulkProgress{Chunk: 0, Chunks: 3, Uploaded: 10, Total: 25}, progress[0])
+	assert.Equal(25, progress[2].Uploaded)
+	assert.Equal(BulkCheckpoint{BatchSize: 10, Total: 25, Done: []int{0, 1, 2}}, checkpoints[2])
+}
+
+func TestAddEntriesChunkedResume(t *testing.T) {
+	c, err := NewClient(&ClientConfig{Token: "fakeToken"})
+	if err != nil {
+		t.FailNow()
+	}
+	assert := assert.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var sent []string
+	fail := true
+	httpmock.RegisterResponder("POST", c.buildUrl("entities/Products/entries", nil), func(req *http.Request) (*http.Response, error) {
+		b, _ := ioutil.ReadAll(req.Body)
+		var entries []Entry
+		json.Unmarshal(b, &entries)
+		if fail && entries[0].Value == "product-10" {
+			return httpmock.NewStringResponse(http.StatusBadRequest, `{}`), nil
+		}
+		sent = append(sent, entries[0].Value)
+		return httpmock.NewStringResponse(200, `{}`), nil
+	})
+
+	entries := bulkEntries(30)
+	checkpoint := &BulkCheckpoint{}
+	err = c.AddEntriesChunked("Products", entries, BulkOptions{BatchSize: 10, Checkpoint: checkpoint})
+
+	assert.Equal(&BulkError{1, &StatusError{StatusCode: 400}}, err)
+	assert.Equal([]string{"product-0"}, sent)
+	assert.Equal([]int{0}, checkpoint.Done)
+
+	fail = false
+	err = c.AddEntriesChunked("Products", entries, BulkOptions{BatchSize: 10, Checkpoint: checkpoint})
+
+	assert.Nil(err)
+	assert.Equal([]string{"product-0", "product-10", "product-20"}, sent)
+	assert.Equal([]int{0, 1, 2}, checkpoint.Done)
+
+	err = c.AddEntriesChunked("Products", entries, BulkOptions{BatchSize: 5, Checkpoint: checkpoint})
+	assert.EqualError(err, "apiai: checkpoint of 30 entries in batches of 10 doesn't match the upload")
+}
+
+func TestUpdateEntityChunked(t *testing.T) {
+	c, err := NewClient(&ClientConfig{Token: "fakeToken"})
+	if err != nil {
+		t.FailNow()
+	}
+	assert := assert.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var mu sync.Mutex
+	var calls []string
+	recorder := func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, req.Method)
+		return httpmock.NewStringResponse(200, `{}`), nil
+	}
+	httpmock.RegisterResponder("PUT", c.buildUrl("entities/Products", nil), recorder)
+	httpmock.RegisterResponder("POST", c.buildUrl("entities/Products/entries", nil), recorder)
+
+	err = c.UpdateEntityChunked("Products", Entity{Name: "Products", Entries: bulkEntries(40)}, BulkOptions{BatchSize: 10, Parallelism: 4})
+
+	assert.Nil(err)
+	assert.Equal([]string{"PUT", "POST", "POST", "POST"}, calls)
+
+	var body string
+	httpmock.RegisterResponder("PUT", c.buildUrl("entities/Products", nil), func(req *http.Request) (*http.Response, error) {
+		b, _ := ioutil.ReadAll(req.Body)
+		body = string(b)
+		return recorder(req)
+	})
+	calls = nil
+	err = c.UpdateEntityChunked("Products", Entity{Name: "Products", IsEnum: true}, BulkOptions{BatchSize: 10})
+
+	assert.Nil(err)
+	assert.Equal([]string{"PUT"}, calls, "entities without entries are still updated")
+	assert.Contains(body, `"entries":[]`)
+	assert.Contains(body, `"isEnum":true`)
+}
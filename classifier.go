@@ -0,0 +1,364 @@
+package apiai
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	defaultMinScore    = 0.25
+	offlineSource      = "offline"
+	numberToken        = "@sys.number"
+	maxTrackedSessions = 10000
+)
+
+type classifierPhrase struct {
+	intent int
+	vector map[string]float64
+}
+
+// Classifier recognizes intents locally, comparing the TF-IDF weighted words
+// and word pairs of queries with the training phrases of the intents. Entity
+// values and numbers are masked, so "a large tea" is as close to "a @size
+// @drink" as to "a small coffee".
+type Classifier struct {
+	// MinScore is the similarity under which queries go to the fallback
+	// intent, defaults to 0.25.
+	MinScore float64
+
+	intents []Intent
+	aliases []map[string]string
+	phrases []classifierPhrase
+	idf     map[string]float64
+	matcher *EntityMatcher
+}
+
+// NewClassifier trains a classifier from the intents and entities of an agent,
+// as exported in an AgentArchive or AgentSnapshot.
+func NewClassifier(intents []Intent, entities []Entity) *Classifier {
+	c := &Classifier{
+		MinScore: defaultMinScore,
+		intents:  intents,
+		idf:      map[string]float64{},
+		matcher:  NewEntityMatcher(entities),
+	}
+	var docs []map[string]float64
+	for i, intent := range intents {
+		aliases := map[string]string{}
+		for _, r := range intent.Responses {
+			for _, p := range r.Params {
+				if p.DataType == "" {
+					continue
+				}
+				if _, ok := aliases[maskEntity(p.DataType)]; !ok {
+					aliases[maskEntity(p.DataType)] = p.Name
+				}
+			}
+		}
+		for _, us := range intent.UserSays {
+			var tokens []string
+			for _, d := range us.Data {
+				if d.Meta != "" {
+					entity := maskEntity(d.Meta)
+					tokens = append(tokens, entity)
+					if _, ok := aliases[entity]; !ok && d.Alias != "" {
+						aliases[entity] = d.Alias
+					}
+					continue
+				}
+				for _, t := range tokenize(d.Text) {
+					tokens = append(tokens, maskNumber(t.text))
+				}
+			}
+			if len(tokens) == 0 {
+				continue
+			}
+			features := termFrequencies(tokens)
+			docs = append(docs, features)
+			c.phrases = append(c.phrases, classifierPhrase{intent: i, vector: features})
+		}
+		c.aliases = append(c.aliases, aliases)
+	}
+
+	df := map[string]int{}
+	for _, doc := range docs {
+		for term := range doc {
+			df[term]++
+		}
+	}
+	for term, n := range df {
+		c.idf[term] = math.Log(float64(len(docs)+1)/float64(n+1)) + 1
+	}
+	for _, p := range c.phrases {
+		c.weigh(p.vector)
+	}
+	return c
+}
+
+// maskEntity returns the token an entity reference is replaced with. Every
+// kind of number is the same token, since queries can't tell them apart.
+func maskEntity(meta string) string {
+	if !strings.HasPrefix(meta, "@") {
+		meta = "@" + meta
+	}
+	if strings.HasPrefix(meta, numberToken) || meta == "@sys.cardinal" || meta == "@sys.ordinal" {
+		return numberToken
+	}
+	return meta
+}
+
+func maskNumber(token string) string {
+	for _, r := range token {
+		if !unicode.IsDigit(r) {
+			return token
+		}
+	}
+	return numberToken
+}
+
+func termFrequencies(tokens []string) map[string]float64 {
+	features := map[string]float64{}
+	for i, t := range tokens {
+		features[t]++
+		if i > 0 {
+			features[tokens[i-1]+" "+t]++
+		}
+	}
+	return features
+}
+
+// weigh turns term frequencies into a unit TF-IDF vector. Terms unknown to
+// the training phrases are dropped.
+func (c *Classifier) weigh(v map[string]float64) {
+	norm := 0.0
+	for term, tf := range v {
+		idf, ok := c.idf[term]
+		if !ok {
+			delete(v, term)
+			continue
+		}
+		v[term] = tf * idf
+		norm += v[term] * v[term]
+	}
+	norm = math.Sqrt(norm)
+	for term := range v {
+		v[term] /= norm
+	}
+}
+
+// Classify answers the query with the intent of the most similar training
+// phrase among the intents whose input contexts are in q.Contexts. Parameters
+// are filled with the entity values and numbers found in the query.
+func (c *Classifier) Classify(q Query) *QueryResponse {
+	text := strings.Join(q.Query, " ")
+	tokens := tokenize(text)
+	spans := c.matcher.Match(text)
+	values := map[string]string{}
+	var masked []string
+	for i := 0; i < len(tokens); i++ {
+		if len(spans) > 0 && tokens[i].start == spans[0].Start {
+			entity := "@" + spans[0].Entity
+			masked = append(masked, entity)
+			values[entity] = spans[0].Value
+			for i < len(tokens)-1 && tokens[i].end < spans[0].End {
+				i++
+			}
+			spans = spans[1:]
+			continue
+		}
+		t := maskNumber(tokens[i].text)
+		if t == numberToken {
+			values[numberToken] = tokens[i].text
+		}
+		masked = append(masked, t)
+	}
+	query := termFrequencies(masked)
+	c.weigh(query)
+
+	best, bestScore := -1, 0.0
+	for _, p := range c.phrases {
+		if !ContextsActive(c.intents[p.intent].Contexts, q.Contexts) {
+			continue
+		}
+		score := 0.0
+		for term, w := range query {
+			score += w * p.vector[term]
+		}
+		if score > bestScore || (score == bestScore && best >= 0 && len(c.intents[p.intent].Contexts) > len(c.intents[best].Contexts)) {
+			best, bestScore = p.intent, score
+		}
+	}
+	if bestScore < c.MinScore {
+		best = c.fallback(q.Contexts)
+	}
+
+	resp := &QueryResponse{
+		Id:        fmt.Sprintf("offline-%d", time.Now().UnixNano()),
+		Timestamp: time.Now().UTC(),
+		Language:  q.Language,
+		SessionId: q.SessionId,
+		Status:    Status{Code: http.StatusOK, ErrorType: "success"},
+		Result: Result{
+			Source:        offlineSource,
+			ResolvedQuery: text,
+			Action:        UnknownInputAction,
+			Params:        map[string]interface{}{},
+			Score:         bestScore,
+		},
+	}
+	if best < 0 {
+		return resp
+	}
+	intent := c.intents[best]
+	resp.Result.Metadata = Metadata{IntentId: intent.Id, IntentName: intent.Name}
+	resp.Result.Action = ""
+	if len(intent.Responses) > 0 {
+		r := intent.Responses[0]
+		resp.Result.Action = r.Action
+		for _, p := range r.Params {
+			resp.Result.Params[p.Name] = ""
+		}
+		for _, ctx := range r.AffectedContexts {
+			if ctx.Lifespan > 0 {
+				resp.Result.Contexts = append(resp.Result.Contexts, ctx)
+			}
+		}
+		resp.Result.Fulfillment.Messages = r.Messages
+		if len(r.Messages) > 0 {
+			resp.Result.Fulfillment.Speech = r.Messages[0].Speech
+		}
+	}
+	for entity, value := range values {
+		if alias, ok := c.aliases[best][entity]; ok {
+			resp.Result.Params[alias] = value
+		}
+	}
+	return resp
+}
+
+func (c *Classifier) fallback(active []Context) int {
+	best := -1
+	for i, intent := range c.intents {
+		if intent.FallbackIntent && ContextsActive(intent.Contexts, active) && (best < 0 || len(intent.Contexts) > len(c.intents[best].Contexts)) {
+			best = i
+		}
+	}
+	return best
+}
+
+func (c *Classifier) resetsContexts(intentName string) bool {
+	for _, i := range c.intents {
+		if intentName != "" && i.Name == intentName {
+			return len(i.Responses) > 0 && i.Responses[0].ResetContexts
+		}
+	}
+	return false
+}
+
+// FallbackClient sends queries to a remote agent and answers them with a local
+// Classifier when the agent fails or doesn't answer within Timeout. Offline
+// answers have "offline" as Result.Source. The active contexts of the last
+// maxTrackedSessions sessions are remembered, decaying and merging them on
+// offline answers as api.ai does, so offline answers can follow the
+// conversation.
+type FallbackClient struct {
+	Remote     Querier
+	Classifier *Classifier
+	Timeout    time.Duration
+	// OnFallback is called, if set, with the error of the remote agent every
+	// time the classifier answers.
+	OnFallback func(q Query, err error)
+
+	mu       sync.Mutex
+	contexts map[string][]Context
+	sessions []string
+}
+
+func NewFallbackClient(remote Querier, classifier *Classifier, timeout time.Duration) *FallbackClient {
+	return &FallbackClient{Remote: remote, Classifier: classifier, Timeout: timeout}
+}
+
+func (c *FallbackClient) Query(q Query) (*QueryResponse, error) {
+	resp, err := c.queryRemote(q)
+	if err != nil {
+		if c.OnFallback != nil {
+			c.OnFallback(q, err)
+		}
+		var active []Context
+		c.mu.Lock()
+		if !q.ResetContexts {
+			active = c.contexts[q.SessionId]
+		}
+		c.mu.Unlock()
+		for _, ctx := range q.Contexts {
+			active = SetContext(active, ctx)
+		}
+		offline := q
+		offline.Contexts = active
+		resp = c.Classifier.Classify(offline)
+		resp.Result.Contexts = c.nextContexts(active, resp)
+	}
+	c.remember(q.SessionId, resp.Result.Contexts)
+	return resp, nil
+}
+
+// nextContexts returns the contexts active after an offline answer: the
+// previous ones lose one unit of lifespan and the matched intent then sets
+// its own.
+func (c *FallbackClient) nextContexts(active []Context, resp *QueryResponse) []Context {
+	next := DecayContexts(active)
+	if c.Classifier.resetsContexts(resp.Result.Metadata.IntentName) {
+		next = nil
+	}
+	for _, ctx := range resp.Result.Contexts {
+		next = SetContext(next, ctx)
+	}
+	return next
+}
+
+// remember keeps the contexts of a session, forgetting the oldest session once
+// maxTrackedSessions are tracked.
+func (c *FallbackClient) remember(sessionId string, contexts []Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.contexts == nil {
+		c.contexts = map[string][]Context{}
+	}
+	if _, ok := c.contexts[sessionId]; !ok {
+		if len(c.sessions) >= maxTrackedSessions {
+			delete(c.contexts, c.sessions[0])
+			c.sessions = c.sessions[1:]
+		}
+		c.sessions = append(c.sessions, sessionId)
+	}
+	c.contexts[sessionId] = contexts
+}
+
+// queryRemote waits for the remote agent at most Timeout. Since queries can't
+// be canceled, a late answer is discarded.
+func (c *FallbackClient) queryRemote(q Query) (*QueryResponse, error) {
+	if c.Timeout <= 0 {
+		return c.Remote.Query(q)
+	}
+	type answer struct {
+		resp *QueryResponse
+		err  error
+	}
+	remote := c.Remote
+	done := make(chan answer, 1)
+	go func() {
+		resp, err := remote.Query(q)
+		done <- answer{resp, err}
+	}()
+	select {
+	case a := <-done:
+		return a.resp, a.err
+	case <-time.After(c.Timeout):
+		return nil, fmt.Errorf("apiai: query timed out after %v", c.Timeout)
+	}
+}
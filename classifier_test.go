@@ -0,0 +1,143 @@
+package apiai
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func classifierAgent() ([]Intent, []Entity) {
+	userSays := func(phrases ...string) []UserSays {
+		var userSays []UserSays
+		for _, p := range phrases {
+			us, _ := ParseUserSays(p)
+			userSays = append(userSays, us)
+		}
+		return userSays
+	}
+	entities := []Entity{{Name: "drink", Entries: []Entry{
+		{Value: "coffee", Synonyms: []string{"coffee", "java"}},
+		{Value: "tea", Synonyms: []string{"tea", "green tea"}},
+	}}}
+	intents := []Intent{
+		{Id: "i1", Name: "greet", UserSays: userSays("hello", "hi there", "good morning"), Responses: []IntentResponse{{
+			Action:   "greet",
+			Messages: []Message{{Type: 0, Speech: "Hello!"}},
+		}}},
+		{Id: "i2", Name: "order", UserSays: userSays("I want @sys.number:count @drink", "can I have a [coffee](@drink:drink)"), Responses: []IntentResponse{{
+			Action:           "order.create",
+			Params:           []IntentParameter{{Name: "drink", DataType: "@drink"}, {Name: "count", DataType: "@sys.number"}, {Name: "size"}},
+			AffectedContexts: []Context{{Name: "ordering", Lifespan: 2}},
+		}}},
+		{Id: "i3", Name: "confirm", Contexts: []string{"ordering"}, UserSays: userSays("yes please", "yes")},
+		{Id: "i4", Name: "fallback", FallbackIntent: true, Responses: []IntentResponse{{Action: "input.unknown"}}},
+	}
+	return intents, entities
+}
+
+func TestClassifier(t *testing.T) {
+	assert := assert.New(t)
+	c := NewClassifier(classifierAgent())
+
+	resp := c.Classify(Query{Query: []string{"I want 2 green teas"}, SessionId: "s1", Language: "en"})
+	assert.Equal("order", resp.Result.Metadata.IntentName)
+	assert.Equal("order.create", resp.Result.Action)
+	assert.Equal(offlineSource, resp.Result.Source)
+	assert.Equal(map[string]interface{}{"drink": "", "count": "2", "size": ""}, resp.Result.Params)
+	assert.Equal([]Context{{Name: "ordering", Lifespan: 2}}, resp.Result.Contexts)
+	assert.Equal("s1", resp.SessionId)
+
+	resp = c.Classify(Query{Query: []string{"can I have a java?"}})
+	assert.Equal("order", resp.Result.Metadata.IntentName)
+	assert.Equal("coffee", resp.Result.Params["drink"])
+	assert.True(resp.Result.Score > 0.9, "masked entities match any value")
+
+	resp = c.Classify(Query{Query: []string{"Hello!"}})
+	assert.Equal("greet", resp.Result.Metadata.IntentName)
+	assert.Equal("Hello!", resp.Result.Fulfillment.Speech)
+	assert.InDelta(1.0, resp.Result.Score, 1e-9)
+
+	resp = c.Classify(Query{Query: []string{"yes"}})
+	assert.Equal("fallback", resp.Result.Metadata.IntentName, "input contexts are required")
+	resp = c.Classify(Query{Query: []string{"yes"}, Contexts: []Context{{Name: "Ordering", Lifespan: 1}}})
+	assert.Equal("confirm", resp.Result.Metadata.IntentName)
+
+	resp = c.Classify(Query{Query: []string{"what is the weather"}})
+	assert.Equal("fallback", resp.Result.Metadata.IntentName)
+
+	resp = NewClassifier(nil, nil).Classify(Query{Query: []string{"hi"}})
+	assert.Equal(UnknownInputAction, resp.Result.Action)
+	assert.Equal(0.0, resp.Result.Score)
+}
+
+func TestFallbackClient(t *testing.T) {
+	assert := assert.New(t)
+
+	remoteErr := errors.New("connection refused")
+	var failed []error
+	remote := queryFunc(func(q Query) (*QueryResponse, error) {
+		switch q.Query[0] {
+		case "slow":
+			time.Sleep(200 * time.Millisecond)
+		case "I want a coffee":
+			return nil, remoteErr
+		}
+		return &QueryResponse{Result: Result{Source: "agent", Action: "remote"}}, nil
+	})
+	c := NewFallbackClient(remote, NewClassifier(classifierAgent()), 50*time.Millisecond)
+	c.OnFallback = func(q Query, err error) { failed = append(failed, err) }
+
+	resp, err := c.Query(Query{Query: []string{"hello"}, SessionId: "s1"})
+	assert.Nil(err)
+	assert.Equal("remote", resp.Result.Action)
+
+	resp, err = c.Query(Query{Query: []string{"I want a coffee"}, SessionId: "s1"})
+	assert.Nil(err)
+	assert.Equal("order.create", resp.Result.Action)
+	assert.Equal("offline", resp.Result.Source)
+
+	resp, err = c.Query(Query{Query: []string{"slow"}, SessionId: "s1"})
+	assert.Nil(err)
+	assert.Equal("offline", resp.Result.Source)
+	assert.Equal([]error{remoteErr, errors.New("apiai: query timed out after 50ms")}, failed)
+
+	c.Timeout = 0
+	c.Remote = queryFunc(func(q Query) (*QueryResponse, error) { return nil, remoteErr })
+	c.contexts["s1"] = []Context{{Name: "ordering", Lifespan: 1}}
+	resp, err = c.Query(Query{Query: []string{"yes please"}, SessionId: "s1"})
+	assert.Nil(err)
+	assert.Equal("confirm", resp.Result.Metadata.IntentName, "contexts of previous answers are remembered")
+}
+
+func TestFallbackClientContexts(t *testing.T) {
+	assert := assert.New(t)
+
+	c := &FallbackClient{
+		Remote:     queryFunc(func(q Query) (*QueryResponse, error) { return nil, errors.New("connection refused") }),
+		Classifier: NewClassifier(classifierAgent()),
+	}
+
+	resp, _ := c.Query(Query{Query: []string{"I want a coffee"}, SessionId: "s1"})
+	assert.Equal([]Context{{Name: "ordering", Lifespan: 2}}, resp.Result.Contexts)
+
+	resp, _ = c.Query(Query{Query: []string{"hello"}, SessionId: "s1", Contexts: []Context{{Name: "greeted", Lifespan: 3}}})
+	assert.Equal("greet", resp.Result.Metadata.IntentName)
+	assert.Equal([]Context{{Name: "ordering", Lifespan: 1}, {Name: "greeted", Lifespan: 2}}, resp.Result.Contexts, "contexts decay and merge")
+
+	resp, _ = c.Query(Query{Query: []string{"yes please"}, SessionId: "s1"})
+	assert.Equal("confirm", resp.Result.Metadata.IntentName)
+	assert.Equal([]Context{{Name: "greeted", Lifespan: 1}}, resp.Result.Contexts)
+
+	resp, _ = c.Query(Query{Query: []string{"yes please"}, SessionId: "s1"})
+	assert.Equal("fallback", resp.Result.Metadata.IntentName, "expired contexts are dropped")
+
+	for i := 0; i < maxTrackedSessions; i++ {
+		c.remember(fmt.Sprint(i), nil)
+	}
+	assert.Len(c.contexts, maxTrackedSessions)
+	assert.NotContains(c.contexts, "s1", "the oldest session is forgotten")
+	assert.Contains(c.contexts, "0")
+}
@@ -3,6 +3,7 @@ package apiai
 import (
 	"fmt"
 	"net/url"
+	"strings"
 )
 
 const baseUrl = "https://api.api.ai/v1/"
@@ -19,12 +20,19 @@ type ClientConfig struct {
 	QueryLang  string
 	SpeechLang string
 	ProxyURL   string
+	BaseURL    string // defaults to https://api.api.ai/v1/
+	Metrics    Metrics
 }
 
 type ApiClient struct {
 	config *ClientConfig
 }
 
+// Querier is implemented by ApiClient and by the clients that wrap it.
+type Querier interface {
+	Query(Query) (*QueryResponse, error)
+}
+
 type Client interface {
 	Query(Query) (*QueryResponse, error)
 	Tts(text string) (string, error)
@@ -42,6 +50,10 @@ type Client interface {
 	AddEntries(idOrName string, entries []Entry) error
 	UpdateEntries(idOrName string, entries []Entry) error
 	DeleteEntries(idOrName string, entries []string) error
+	CreateUserEntities(entities []UserEntity, sessionId string) error
+	GetUserEntity(name, sessionId string) (*UserEntity, error)
+	UpdateUserEntity(name string, entity UserEntity, sessionId string) error
+	DeleteUserEntity(name, sessionId string) error
 	GetIntent(id string) (*Intent, error)
 	CreateIntent(intent Intent) (*CreationResponse, error)
 	UpdateIntent(id string, intent Intent) error
@@ -56,6 +68,11 @@ func NewClient(conf *ClientConfig) (*ApiClient, error) {
 	if conf.Version == "" {
 		conf.Version = defaultVersion
 	}
+	if conf.BaseURL == "" {
+		conf.BaseURL = baseUrl
+	} else if !strings.HasSuffix(conf.BaseURL, "/") {
+		conf.BaseURL += "/"
+	}
 	if conf.QueryLang == "" {
 		conf.QueryLang = defaultQueryLang
 	}
@@ -82,7 +99,7 @@ func languageAvailable(inputLang string, languages []string) bool {
 }
 
 func (c *ApiClient) buildUrl(endpoint string, params map[string]string) string {
-	u := baseUrl + endpoint + "?v=" + c.config.Version
+	u := c.config.BaseURL + endpoint + "?v=" + c.config.Version
 	if params != nil {
 		for i, v := range params {
 			u += "&" + i + "=" + url.QueryEscape(v)
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcossegovia/apiai-go"
+)
+
+const chatHelp = `Type a message to query the agent, or a command:
+  /event NAME                          send an event
+  /context set NAME [LIFESPAN] [K=V]   set a context
+  /context clear [NAME]                clear one or every context
+  /reset                               start a new session
+  /lang LANG                           change the query language
+  /history                             show the conversation
+  /save FILE                           save the conversation as a test
+  /quit
+`
+
+type chat struct {
+	*cli
+	session    string
+	conv       *apiai.Conversation
+	resetNext  bool
+	unreplayed bool
+}
+
+// chat runs an interactive session with the agent reading stdin line by line.
+func (c *cli) chat(args []string) error {
+	fs := flag.NewFlagSet("chat", flag.ContinueOnError)
+	session := fs.String("session", "", "")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) > 0 {
+		return errUsage
+	}
+	ch := &chat{cli: c}
+	ch.reset(*session)
+	fmt.Fprintf(c.out, "Chatting in session %s, /help for commands.\n", ch.session)
+
+	scanner := bufio.NewScanner(c.stdin)
+	for {
+		fmt.Fprint(c.out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(c.out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if line == "/quit" || line == "/exit" {
+			return nil
+		}
+		if err := ch.handle(line); err != nil {
+			fmt.Fprintf(c.out, "error: %v\n", err)
+		}
+	}
+}
+
+// reset starts a new conversation in session, or in a new session when empty.
+// Only new sessions are reset, an existing one keeps its contexts.
+func (ch *chat) reset(session string) {
+	ch.resetNext = session == ""
+	if session == "" {
+		session = fmt.Sprintf("chat-%d", time.Now().UnixNano())
+	}
+	ch.session = session
+	ch.conv = &apiai.Conversation{Name: "chat " + time.Now().Format("2006-01-02 15:04")}
+	ch.unreplayed = false
+}
+
+func (ch *chat) handle(line string) error {
+	if !strings.HasPrefix(line, "/") {
+		return ch.send(apiai.Query{Query: []string{line}})
+	}
+	fields := strings.Fields(line)
+	switch {
+	case fields[0] == "/help":
+		fmt.Fprint(ch.out, chatHelp)
+	case fields[0] == "/event" && len(fields) == 2:
+		return ch.send(apiai.Query{Event: apiai.Event{Name: fields[1]}})
+	case fields[0] == "/context" && len(fields) >= 3 && fields[1] == "set":
+		return ch.setContext(fields[2], fields[3:])
+	case fields[0] == "/context" && len(fields) == 2 && fields[1] == "clear":
+		return ch.client.DeleteContexts(ch.session)
+	case fields[0] == "/context" && len(fields) == 3 && fields[1] == "clear":
+		return ch.client.DeleteContext(fields[2], ch.session)
+	case fields[0] == "/reset" && len(fields) == 1:
+		ch.reset("")
+		fmt.Fprintf(ch.out, "New session %s.\n", ch.session)
+	case fields[0] == "/lang" && len(fields) == 2:
+		config := ch.config
+		config.QueryLang = fields[1]
+		client, err := apiai.NewClient(&config)
+		if err != nil {
+			return err
+		}
+		ch.client, ch.config = client, config
+	case fields[0] == "/history" && len(fields) == 1:
+		for i, turn := range ch.conv.Turns {
+			said := turn.Say
+			if turn.Event != "" {
+				said = "event " + turn.Event
+			}
+			fmt.Fprintf(ch.out, "%d. %s -> %s (%s)\n", i+1, said, turn.Intent, turn.Action)
+		}
+	case fields[0] == "/save" && len(fields) == 2:
+		return ch.save(fields[1])
+	default:
+		return fmt.Errorf("unknown command %q, /help for commands", line)
+	}
+	return nil
+}
+
+func (ch *chat) setContext(name string, args []string) error {
+	ctx := apiai.Context{Name: name, Lifespan: 5, Params: map[string]interface{}{}}
+	for _, arg := range args {
+		if lifespan, err := strconv.Atoi(arg); err == nil {
+			ctx.Lifespan = lifespan
+			continue
+		}
+		i := strings.Index(arg, "=")
+		if i <= 0 {
+			return fmt.Errorf("parameter %q is not KEY=VALUE", arg)
+		}
+		ctx.Params[arg[:i]] = arg[i+1:]
+	}
+	if ch.resetNext {
+		// Reset the session now, or the next query would drop this context.
+		if err := ch.client.DeleteContexts(ch.session); err != nil {
+			return err
+		}
+		ch.resetNext = false
+	}
+	ch.unreplayed = true
+	return ch.client.CreateContext(ctx, ch.session)
+}
+
+func (ch *chat) send(q apiai.Query) error {
+	q.SessionId = ch.session
+	q.ResetContexts = ch.resetNext
+	resp, err := ch.client.Query(q)
+	if err != nil {
+		return err
+	}
+	ch.resetNext = false
+	ch.conv.Turns = append(ch.conv.Turns, apiai.RecordTurn(q, resp))
+
+	r := resp.Result
+	fmt.Fprintf(ch.out, "agent: %s\n", r.Fulfillment.Speech)
+	fmt.Fprintf(ch.out, "  intent %s, action %s, score %.2f\n", r.Metadata.IntentName, r.Action, r.Score)
+	if len(r.Params) > 0 {
+		var params []string
+		for _, k := range sortedKeys(r.Params) {
+			params = append(params, fmt.Sprintf("%s=%v", k, r.Params[k]))
+		}
+		fmt.Fprintf(ch.out, "  parameters %s\n", strings.Join(params, " "))
+	}
+	if len(r.Contexts) > 0 {
+		var contexts []string
+		for _, ctx := range r.Contexts {
+			contexts = append(contexts, fmt.Sprintf("%s(%d)", ctx.Name, ctx.Lifespan))
+		}
+		sort.Strings(contexts)
+		fmt.Fprintf(ch.out, "  contexts %s\n", strings.Join(contexts, " "))
+	}
+	return nil
+}
+
+// save writes the conversation as a script expecting the same answers.
+// Contexts set by hand aren't part of scripts, so such conversations may not
+// replay the same.
+func (ch *chat) save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := ch.conv.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(ch.out, "Saved %d turns to %s.\n", len(ch.conv.Turns), path)
+	if ch.unreplayed {
+		fmt.Fprintln(ch.out, "warning: contexts set with /context aren't saved, the conversation may not replay the same")
+	}
+	return nil
+}
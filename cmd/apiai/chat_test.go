@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/marcossegovia/apiai-go"
+	"github.com/marcossegovia/apiai-go/apiaitest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChat(t *testing.T) {
+	assert := assert.New(t)
+
+	order, _ := apiai.ParseUserSays("I want a [coffee](@drink:drink)")
+	agent := func() *apiaitest.FakeAgent {
+		return apiaitest.NewFakeAgent([]apiai.Intent{
+			{Name: "welcome", Events: []apiai.Event{{Name: "WELCOME"}}, Responses: []apiai.IntentResponse{{
+				Action:   "input.welcome",
+				Messages: []apiai.Message{{Type: 0, Speech: "Hello!"}},
+			}}},
+			{Name: "order", UserSays: []apiai.UserSays{order}, Responses: []apiai.IntentResponse{{
+				Action:           "order.create",
+				AffectedContexts: []apiai.Context{{Name: "ordering", Lifespan: 2}},
+				Messages:         []apiai.Message{{Type: 0, Speech: "Which size?"}},
+			}}},
+		})
+	}
+	server := httptest.NewServer(agent())
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "apiai-chat")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	script := filepath.Join(dir, "chat.yaml")
+
+	input := strings.Join([]string{
+		"/event WELCOME",
+		"I want a coffee",
+		"/context set paying",
+		"/history",
+		"/lang xx",
+		"/frobnicate",
+		"/save " + script,
+		"/quit",
+		"never sent",
+	}, "\n")
+	ioutil.WriteFile(filepath.Join(dir, ".apiai.yaml"), []byte("baseUrl: "+server.URL+"\n"), 0644)
+	env := map[string]string{"APIAI_TOKEN": "fakeToken", "HOME": dir}
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	code := run([]string{"chat", "--session", "s1"}, strings.NewReader(input), stdout, stderr, func(k string) string { return env[k] })
+	assert.Equal(exitOK, code)
+	out := stdout.String()
+	assert.Contains(out, "Chatting in session s1")
+	assert.Contains(out, "agent: Which size?\n  intent order, action order.create, score 1.00\n  parameters drink=coffee\n  contexts ordering(2)\n")
+	assert.Contains(out, "error: apiai: wops something happens because status code is 404")
+	assert.Contains(out, "1. event WELCOME -> welcome (input.welcome)\n2. I want a coffee -> order (order.create)\n")
+	assert.Contains(out, "error: You have to provide a valid query language")
+	assert.Contains(out, `error: unknown command "/frobnicate"`)
+	assert.Contains(out, "Saved 2 turns to "+script)
+	assert.Contains(out, "warning: contexts set with /context aren't saved")
+	assert.NotContains(out, "never sent")
+
+	conv, err := apiai.LoadConversationFile(script)
+	assert.Nil(err)
+	assert.Len(conv.Turns, 2)
+	assert.True(apiaitest.AssertConversation(t, agent(), conv))
+}
+
+func TestChatContextBeforeQuery(t *testing.T) {
+	assert := assert.New(t)
+	server, requests := fakeAPI()
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "apiai-chat")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, ".apiai.yaml"), []byte("baseUrl: "+server.URL+"\n"), 0644)
+	env := map[string]string{"APIAI_TOKEN": "fakeToken", "HOME": dir}
+	input := strings.NewReader("/context set paying 3\nI want a coffee\n/reset\n/context set paying\nI want a coffee\n")
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	code := run([]string{"chat", "--session", "s1"}, input, stdout, stderr, func(k string) string { return env[k] })
+	assert.Equal(exitOK, code)
+
+	var calls []string
+	for _, r := range *requests {
+		calls = append(calls, r.method+" "+r.path)
+	}
+	assert.Equal([]string{"POST /contexts", "POST /query", "DELETE /contexts", "POST /contexts", "POST /query"}, calls)
+	assert.Equal("s1", (*requests)[0].session)
+	assert.Contains((*requests)[0].body, `"name":"paying"`)
+	assert.Contains((*requests)[1].body, `"resetContexts":false`, "existing sessions aren't reset")
+	assert.NotEqual("s1", (*requests)[2].session, "new sessions are reset before setting contexts")
+	assert.Contains((*requests)[4].body, `"resetContexts":false`, "the context set first is kept")
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/marcossegovia/apiai-go"
+)
+
+const defaultSession = "apiai-cli"
+
+type cli struct {
+	client *apiai.ApiClient
+	config apiai.ClientConfig
+	stdin  io.Reader
+	out    io.Writer
+	json   bool
+}
+
+func (c *cli) dispatch(command string, args []string) error {
+	switch command {
+	case "query":
+		return c.query(args)
+	case "tts":
+		return c.tts(args)
+	case "intents":
+		return c.intents(args)
+	case "entities":
+		return c.entities(args)
+	case "contexts":
+		return c.contexts(args)
+	case "chat":
+		return c.chat(args)
+	}
+	return errUsage
+}
+
+func (c *cli) query(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	session := fs.String("session", defaultSession, "")
+	event := fs.String("event", "", "")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	q := apiai.Query{SessionId: *session}
+	switch {
+	case *event != "" && len(positional) == 0:
+		q.Event = apiai.Event{Name: *event}
+	case *event == "" && len(positional) == 1:
+		q.Query = positional
+	default:
+		return errUsage
+	}
+	resp, err := c.client.Query(q)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.writeJSON(resp)
+	}
+	return c.writeResult(resp.Result)
+}
+
+func (c *cli) writeResult(r apiai.Result) error {
+	t := newTable(c.out)
+	t.row("intent", r.Metadata.IntentName)
+	t.row("action", r.Action)
+	t.row("score", fmt.Sprint(r.Score))
+	t.row("speech", r.Fulfillment.Speech)
+	for _, name := range sortedKeys(r.Params) {
+		t.row("param "+name, fmt.Sprint(r.Params[name]))
+	}
+	for _, ctx := range r.Contexts {
+		t.row("context", fmt.Sprintf("%s (lifespan %d)", ctx.Name, ctx.Lifespan))
+	}
+	return t.flush()
+}
+
+func (c *cli) tts(args []string) error {
+	fs := flag.NewFlagSet("tts", flag.ContinueOnError)
+	output := fs.String("o", "", "")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errUsage
+	}
+	path, err := c.client.Tts(positional[0])
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		fmt.Fprintln(c.out, path)
+		return nil
+	}
+	// The speech is written to a temporary file, which may be on another
+	// device, so it's copied rather than renamed.
+	if err := copyFile(path, *output); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (c *cli) intents(args []string) error {
+	fs := flag.NewFlagSet("intents", flag.ContinueOnError)
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) == 0 {
+		return errUsage
+	}
+	switch {
+	case positional[0] == "list" && len(positional) == 1:
+		intents, err := c.client.GetIntents()
+		if err != nil {
+			return err
+		}
+		if c.json {
+			return c.writeJSON(intents)
+		}
+		t := newTable(c.out)
+		t.row("ID", "NAME", "CONTEXTS", "ACTIONS")
+		for _, i := range intents {
+			t.row(i.Id, i.Name, strings.Join(i.ContextIn, ","), strings.Join(i.Actions, ","))
+		}
+		return t.flush()
+	case positional[0] == "get" && len(positional) == 2:
+		intent, err := c.client.GetIntent(positional[1])
+		if err != nil {
+			return err
+		}
+		return c.writeJSON(intent)
+	case positional[0] == "create" && len(positional) == 2:
+		var intent apiai.Intent
+		if err := c.readJSON(positional[1], &intent); err != nil {
+			return err
+		}
+		resp, err := c.client.CreateIntent(intent)
+		if err != nil {
+			return err
+		}
+		return c.writeCreated(resp)
+	case positional[0] == "delete" && len(positional) == 2:
+		return c.client.DeleteIntent(positional[1])
+	}
+	return errUsage
+}
+
+func (c *cli) entities(args []string) error {
+	fs := flag.NewFlagSet("entities", flag.ContinueOnError)
+	replace := fs.Bool("replace", false, "")
+	output := fs.String("o", "", "")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) == 0 {
+		return errUsage
+	}
+	switch {
+	case positional[0] == "list" && len(positional) == 1:
+		entities, err := c.client.GetEntities()
+		if err != nil {
+			return err
+		}
+		if c.json {
+			return c.writeJSON(entities)
+		}
+		t := newTable(c.out)
+		t.row("ID", "NAME", "ENTRIES", "PREVIEW")
+		for _, e := range entities {
+			t.row(e.Id, e.Name, fmt.Sprint(e.Count), e.Preview)
+		}
+		return t.flush()
+	case positional[0] == "get" && len(positional) == 2:
+		entity, err := c.client.GetEntity(positional[1])
+		if err != nil {
+			return err
+		}
+		return c.writeJSON(entity)
+	case positional[0] == "import" && len(positional) == 3:
+		return c.importEntries(positional[1], positional[2], *replace)
+	case positional[0] == "export" && len(positional) == 2:
+		return c.exportEntries(positional[1], *output)
+	}
+	return errUsage
+}
+
+// importEntries reads entries from a CSV or JSON file, by extension, and adds
+// them to the entity, creating it if needed. With replace the entries of the
+// entity are replaced.
+func (c *cli) importEntries(name, path string, replace bool) error {
+	f, err := c.open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var reader apiai.EntryReader = apiai.NewCSVEntryReader(f)
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		reader = apiai.NewJSONEntryReader(f)
+	}
+	entries, err := apiai.ReadEntries(reader)
+	if err != nil {
+		return err
+	}
+
+	entity, err := c.client.GetEntity(name)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	switch {
+	case err != nil:
+		// Large entities are created with their first entry and filled in
+		// chunks.
+		first := entries
+		if len(first) > 1 {
+			first = first[:1]
+		}
+		resp, err := c.client.CreateEntity(apiai.Entity{Name: name, Entries: first})
+		if err != nil {
+			return err
+		}
+		if len(entries) > 1 {
+			if err := c.client.AddEntriesChunked(resp.Id, entries[1:], apiai.BulkOptions{}); err != nil {
+				return err
+			}
+		}
+	case replace:
+		entity.Entries = entries
+		if err := c.client.UpdateEntityChunked(entity.Id, *entity, apiai.BulkOptions{}); err != nil {
+			return err
+		}
+	default:
+		if err := c.client.AddEntriesChunked(entity.Id, entries, apiai.BulkOptions{}); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(c.out, "imported %d entries to %s\n", len(entries), name)
+	return nil
+}
+
+func (c *cli) exportEntries(name, path string) error {
+	entity, err := c.client.GetEntity(name)
+	if err != nil {
+		return err
+	}
+	w := c.out
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if c.json || strings.EqualFold(filepath.Ext(path), ".json") {
+		return apiai.WriteEntriesJSON(w, entity.Entries)
+	}
+	return apiai.WriteEntriesCSV(w, entity.Entries)
+}
+
+func (c *cli) contexts(args []string) error {
+	fs := flag.NewFlagSet("contexts", flag.ContinueOnError)
+	session := fs.String("session", "", "")
+	lifespan := fs.Int("lifespan", 5, "")
+	var params paramsFlag
+	fs.Var(&params, "param", "")
+	positional, err := parseArgs(fs, args)
+	if err != nil || len(positional) == 0 || *session == "" {
+		return errUsage
+	}
+	switch {
+	case positional[0] == "list" && len(positional) == 1:
+		contexts, err := c.client.GetContexts(*session)
+		if err != nil {
+			return err
+		}
+		if c.json {
+			return c.writeJSON(contexts)
+		}
+		t := newTable(c.out)
+		t.row("NAME", "LIFESPAN", "PARAMETERS")
+		for _, ctx := range contexts {
+			var pairs []string
+			for _, k := range sortedKeys(ctx.Params) {
+				pairs = append(pairs, fmt.Sprintf("%s=%v", k, ctx.Params[k]))
+			}
+			t.row(ctx.Name, fmt.Sprint(ctx.Lifespan), strings.Join(pairs, " "))
+		}
+		return t.flush()
+	case positional[0] == "set" && len(positional) == 2:
+		ctx := apiai.Context{Name: positional[1], Lifespan: *lifespan, Params: map[string]interface{}{}}
+		for k, v := range params {
+			ctx.Params[k] = v
+		}
+		return c.client.CreateContext(ctx, *session)
+	case positional[0] == "clear" && len(positional) == 1:
+		return c.client.DeleteContexts(*session)
+	case positional[0] == "clear" && len(positional) == 2:
+		return c.client.DeleteContext(positional[1], *session)
+	}
+	return errUsage
+}
+
+// paramsFlag collects repeated KEY=VALUE flags.
+type paramsFlag map[string]string
+
+func (p *paramsFlag) String() string {
+	return fmt.Sprint(map[string]string(*p))
+}
+
+func (p *paramsFlag) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return fmt.Errorf("parameter %q is not KEY=VALUE", s)
+	}
+	if *p == nil {
+		*p = paramsFlag{}
+	}
+	(*p)[s[:i]] = s[i+1:]
+	return nil
+}
+
+func (c *cli) writeCreated(resp *apiai.CreationResponse) error {
+	if c.json {
+		return c.writeJSON(resp)
+	}
+	fmt.Fprintln(c.out, resp.Id)
+	return nil
+}
+
+func (c *cli) writeJSON(v interface{}) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// open opens a file, or stdin for "-".
+func (c *cli) open(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(c.stdin), nil
+	}
+	return os.Open(path)
+}
+
+func (c *cli) readJSON(path string, v interface{}) error {
+	f, err := c.open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("apiai: invalid %s, %v", path, err)
+	}
+	return nil
+}
+
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
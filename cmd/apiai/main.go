@@ -0,0 +1,187 @@
+// Command apiai queries and manages api.ai agents from the command line.
+//
+//	apiai [global flags] <command> [arguments] [flags]
+//
+// The agent token is read from --token, the APIAI_TOKEN environment variable
+// or the token key of the config file, ~/.apiai.yaml by default.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/marcossegovia/apiai-go"
+	"gopkg.in/yaml.v2"
+)
+
+const usage = `usage: apiai [global flags] <command> [arguments] [flags]
+
+commands:
+  query TEXT [--session ID] [--event NAME]
+  tts TEXT [-o FILE]
+  intents list | get ID | create FILE | delete ID
+  entities list | get ID | import NAME FILE [--replace] | export NAME [-o FILE]
+  contexts list | set NAME [--lifespan N] [--param KEY=VALUE] | clear [NAME]  --session ID
+  chat [--session ID]
+
+global flags:
+  --token TOKEN    agent token, defaults to $APIAI_TOKEN
+  --config FILE    config file, defaults to $APIAI_CONFIG or ~/.apiai.yaml
+  --lang LANG      query language
+  --output FORMAT  table or json, defaults to table
+`
+
+// Exit codes, API errors are mapped from their HTTP status.
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+	exitAuth
+	exitNotFound
+	exitBadRequest
+	exitRateLimit
+	exitServer
+)
+
+var errUsage = errors.New("invalid usage")
+
+type config struct {
+	Token      string `yaml:"token"`
+	QueryLang  string `yaml:"lang"`
+	SpeechLang string `yaml:"speechLang"`
+	BaseURL    string `yaml:"baseUrl"`
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr, os.Getenv))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer, getenv func(string) string) int {
+	global := flag.NewFlagSet("apiai", flag.ContinueOnError)
+	global.SetOutput(ioutil.Discard)
+	token := global.String("token", "", "")
+	configFile := global.String("config", "", "")
+	lang := global.String("lang", "", "")
+	output := global.String("output", "table", "")
+	if err := global.Parse(args); err != nil || global.NArg() == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(stderr, "apiai: unknown output format %q\n", *output)
+		return exitUsage
+	}
+
+	conf, err := loadConfig(*configFile, getenv)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	if env := getenv("APIAI_TOKEN"); env != "" {
+		conf.Token = env
+	}
+	if *token != "" {
+		conf.Token = *token
+	}
+	if *lang != "" {
+		conf.QueryLang = *lang
+	}
+	clientConfig := &apiai.ClientConfig{
+		Token:      conf.Token,
+		QueryLang:  conf.QueryLang,
+		SpeechLang: conf.SpeechLang,
+		BaseURL:    conf.BaseURL,
+	}
+	client, err := apiai.NewClient(clientConfig)
+	if err != nil {
+		fmt.Fprintf(stderr, "apiai: %v\n", err)
+		return exitUsage
+	}
+
+	cli := &cli{client: client, config: *clientConfig, stdin: stdin, out: stdout, json: *output == "json"}
+	err = cli.dispatch(global.Arg(0), global.Args()[1:])
+	switch {
+	case err == nil:
+		return exitOK
+	case err == errUsage:
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	default:
+		fmt.Fprintln(stderr, err)
+		return exitCode(err)
+	}
+}
+
+// loadConfig reads the config file, which is optional unless given
+// explicitly.
+func loadConfig(path string, getenv func(string) string) (*config, error) {
+	explicit := path != ""
+	if path == "" {
+		path = getenv("APIAI_CONFIG")
+		explicit = path != ""
+	}
+	if path == "" {
+		path = filepath.Join(getenv("HOME"), ".apiai.yaml")
+	}
+	conf := &config{}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && !explicit {
+		return conf, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.UnmarshalStrict(b, conf); err != nil {
+		return nil, fmt.Errorf("apiai: invalid config file %s, %v", path, err)
+	}
+	return conf, nil
+}
+
+func exitCode(err error) int {
+	var statusErr *apiai.StatusError
+	if !errors.As(err, &statusErr) {
+		return exitError
+	}
+	switch status := statusErr.StatusCode; {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return exitAuth
+	case status == http.StatusNotFound:
+		return exitNotFound
+	case status == http.StatusTooManyRequests:
+		return exitRateLimit
+	case status >= 500:
+		return exitServer
+	case status >= 400:
+		return exitBadRequest
+	}
+	return exitError
+}
+
+func isNotFound(err error) bool {
+	var statusErr *apiai.StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
+
+// parseArgs parses flags placed anywhere among the arguments and returns the
+// positional ones.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(ioutil.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, errUsage
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/marcossegovia/apiai-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type request struct {
+	method, path, session, body string
+}
+
+func fakeAPI() (*httptest.Server, *[]request) {
+	type response struct {
+		status int
+		body   string
+	}
+	var requests []request
+	responses := map[string]response{}
+	handle := func(method, path string, status int, body string) {
+		responses[method+" "+path] = response{status, body}
+	}
+	handle("POST", "/query", 200, `{"result": {"action": "order.create", "score": 0.9, "parameters": {"drink": "coffee"}, "metadata": {"intentName": "order"}, "fulfillment": {"speech": "Which size?"}, "contexts": [{"name": "ordering", "lifespan": 2}]}}`)
+	handle("GET", "/intents", 200, `[{"id": "i1", "name": "order", "contextIn": [], "actions": ["order.create"]}]`)
+	handle("GET", "/intents/missing", 404, `{}`)
+	handle("GET", "/entities/drink", 200, `{"id": "e1", "name": "drink", "entries": [{"value": "coffee", "synonyms": ["coffee", "java"]}]}`)
+	handle("PUT", "/entities/e1", 200, `{}`)
+	handle("POST", "/contexts", 200, `{}`)
+	handle("DELETE", "/contexts", 200, `{}`)
+	handle("GET", "/contexts", 401, `{}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, ok := responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, request{r.Method, r.URL.Path, r.URL.Query().Get("sessionId"), string(b)})
+		w.WriteHeader(resp.status)
+		w.Write([]byte(resp.body))
+	}))
+	return server, &requests
+}
+
+func TestRun(t *testing.T) {
+	assert := assert.New(t)
+	server, requests := fakeAPI()
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "apiai-cli")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	configFile := filepath.Join(dir, "config.yaml")
+	ioutil.WriteFile(configFile, []byte("token: fromConfig\nbaseUrl: "+server.URL+"\n"), 0644)
+	entriesFile := filepath.Join(dir, "drinks.csv")
+	ioutil.WriteFile(entriesFile, []byte("tea,tea,green tea\n"), 0644)
+
+	env := map[string]string{"APIAI_CONFIG": configFile}
+	apiai := func(args ...string) (int, string, string) {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		code := run(args, strings.NewReader(""), stdout, stderr, func(k string) string { return env[k] })
+		return code, stdout.String(), stderr.String()
+	}
+
+	code, out, _ := apiai("query", "a coffee", "--session", "s1")
+	assert.Equal(exitOK, code)
+	assert.Equal(`intent       order
+action       order.create
+score        0.9
+speech       Which size?
+param drink  coffee
+context      ordering (lifespan 2)
+`, out)
+	assert.Contains((*requests)[0].body, `"sessionId":"s1"`)
+
+	code, out, _ = apiai("intents", "list")
+	assert.Equal(exitOK, code)
+	assert.Equal("ID  NAME   CONTEXTS  ACTIONS\ni1  order            order.create\n", out)
+
+	code, out, _ = apiai("--output", "json", "intents", "list")
+	assert.Equal(exitOK, code)
+	assert.Contains(out, `"name": "order"`)
+
+	code, _, errOut := apiai("intents", "get", "missing")
+	assert.Equal(exitNotFound, code)
+	assert.Equal("apiai: wops something happens because status code is 404\n", errOut)
+
+	code, out, _ = apiai("entities", "export", "drink")
+	assert.Equal(exitOK, code)
+	assert.Equal("coffee,coffee,java\n", out)
+
+	*requests = nil
+	code, out, _ = apiai("entities", "import", "drink", entriesFile, "--replace")
+	assert.Equal(exitOK, code)
+	assert.Equal("imported 1 entries to drink\n", out)
+	assert.Equal("PUT", (*requests)[1].method)
+	assert.Contains((*requests)[1].body, `"entries":[{"value":"tea","synonyms":["tea","green tea"]}]`)
+
+	*requests = nil
+	code, _, _ = apiai("contexts", "set", "ordering", "--session", "s1", "--param", "drink=tea", "--lifespan", "2")
+	assert.Equal(exitOK, code)
+	assert.Equal("s1", (*requests)[0].session)
+	assert.Contains((*requests)[0].body, `"name":"ordering","lifespan":2,"parameters":{"drink":"tea"}`)
+
+	env["APIAI_TOKEN"] = "fromEnv"
+	code, _, _ = apiai("contexts", "list", "--session", "s1")
+	assert.Equal(exitAuth, code)
+
+	code, _, errOut = apiai("contexts", "list")
+	assert.Equal(exitUsage, code, "contexts need a session")
+	assert.True(strings.HasPrefix(errOut, "usage: apiai"))
+
+	code, _, _ = apiai("frobnicate")
+	assert.Equal(exitUsage, code)
+	code, _, _ = apiai("--output", "xml", "intents", "list")
+	assert.Equal(exitUsage, code)
+}
+
+func TestLoadConfig(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(k string) string { return vars[k] }
+	}
+	conf, err := loadConfig("", env(map[string]string{"HOME": "/nonexistent"}))
+	assert.Nil(t, err)
+	assert.Equal(t, &config{}, conf, "the default config file is optional")
+
+	_, err = loadConfig("", env(map[string]string{"APIAI_CONFIG": "/nonexistent/apiai.yaml"}))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{&apiai.StatusError{StatusCode: 403}, exitAuth},
+		{fmt.Errorf(`apiai: error on intent "x", %w`, &apiai.StatusError{StatusCode: 400}), exitBadRequest},
+		{&apiai.BulkError{Chunk: 1, Err: &apiai.StatusError{StatusCode: 404}}, exitNotFound},
+		{&apiai.StatusError{StatusCode: 429}, exitRateLimit},
+		{&apiai.StatusError{StatusCode: 503}, exitServer},
+		{errors.New("apiai: wops something happens because status code is 404"), exitError},
+		{errors.New("dial tcp: connection refused"), exitError},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.code, exitCode(test.err), test.err.Error())
+	}
+}
@@ -0,0 +1,234 @@
+package apiai
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const systemEntityPrefix = "sys."
+
+var entityReference = regexp.MustCompile(`(?:^|[\s(])@([^\s:@)]*)(?::([^\s@)]*))?`)
+var referenceName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// EntityReference is a reference to another entity inside an entry of a
+// composite entity, e.g. @sys.number:amount.
+type EntityReference struct {
+	Entity string
+	Alias  string
+}
+
+func (r EntityReference) System() bool {
+	return strings.HasPrefix(r.Entity, systemEntityPrefix)
+}
+
+func (r EntityReference) String() string {
+	if r.Alias == "" {
+		return "@" + r.Entity
+	}
+	return "@" + r.Entity + ":" + r.Alias
+}
+
+// ParseEntityReferences returns the entity references found in text.
+func ParseEntityReferences(text string) ([]EntityReference, error) {
+	var refs []EntityReference
+	aliases := map[string]bool{}
+	for _, m := range entityReference.FindAllStringSubmatch(text, -1) {
+		ref := strings.TrimLeft(m[0], " \t\n(")
+		if !referenceName.MatchString(m[1]) {
+			return nil, fmt.Errorf("apiai: invalid entity reference %q in %q", ref, text)
+		}
+		if strings.Contains(ref, ":") && !referenceName.MatchString(m[2]) {
+			return nil, fmt.Errorf("apiai: invalid alias in entity reference %q in %q", ref, text)
+		}
+		if m[2] != "" {
+			if aliases[m[2]] {
+				return nil, fmt.Errorf("apiai: duplicate alias %q in %q", m[2], text)
+			}
+			aliases[m[2]] = true
+		}
+		refs = append(refs, EntityReference{Entity: m[1], Alias: m[2]})
+	}
+	return refs, nil
+}
+
+// References returns the entities referenced by the entries of e. Only enum
+// entities can be composite; in mapping entities a synonym like @john is
+// plain text.
+func (e Entity) References() ([]EntityReference, error) {
+	if !e.IsEnum {
+		return nil, nil
+	}
+	var refs []EntityReference
+	for _, entry := range e.Entries {
+		texts := []string{entry.Value}
+		for _, s := range entry.Synonyms {
+			if s != entry.Value {
+				texts = append(texts, s)
+			}
+		}
+		for _, text := range texts {
+			r, err := ParseEntityReferences(text)
+			if err != nil {
+				return nil, fmt.Errorf("apiai: entity %q: %v", e.Name, strings.TrimPrefix(err.Error(), "apiai: "))
+			}
+			refs = append(refs, r...)
+		}
+	}
+	return refs, nil
+}
+
+func (e Entity) IsComposite() bool {
+	refs, err := e.References()
+	return err == nil && len(refs) > 0
+}
+
+// ResolveEntityDependencies checks that every entity referenced by a composite
+// entity is either a system entity or one of entities, and returns entities
+// sorted so that each entity comes after the ones it references.
+func ResolveEntityDependencies(entities []Entity) ([]Entity, error) {
+	byName := make(map[string]int, len(entities))
+	for i, e := range entities {
+		byName[e.Name] = i
+	}
+	deps := make([][]int, len(entities))
+	for i, e := range entities {
+		refs, err := e.References()
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range refs {
+			if r.System() {
+				continue
+			}
+			j, ok := byName[r.Entity]
+			if !ok {
+				return nil, fmt.Errorf("apiai: entity %q references unknown entity %q", e.Name, r.Entity)
+			}
+			// Recursive entities may refer to themselves.
+			if j != i {
+				deps[i] = append(deps[i], j)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(entities))
+	sorted := make([]Entity, 0, len(entities))
+	var visit func(i int, path []string) error
+	visit = func(i int, path []string) error {
+		path = append(path, entities[i].Name)
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("apiai: circular entity references %s", strings.Join(path, " -> "))
+		case visited:
+			return nil
+		}
+		state[i] = visiting
+		for _, j := range deps[i] {
+			if err := visit(j, path); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		sorted = append(sorted, entities[i])
+		return nil
+	}
+	for i := range entities {
+		if err := visit(i, nil); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// EntityBuilder builds entities checking that composite entries only refer to
+// system entities or to entities it has been told about.
+type EntityBuilder struct {
+	entity    Entity
+	known     map[string]bool
+	composite bool
+}
+
+func NewEntityBuilder(name string) *EntityBuilder {
+	return &EntityBuilder{entity: Entity{Name: name}, known: map[string]bool{name: true}}
+}
+
+// Known declares entities that entries may reference.
+func (b *EntityBuilder) Known(entities ...Entity) *EntityBuilder {
+	for _, e := range entities {
+		b.known[e.Name] = true
+	}
+	return b
+}
+
+// KnownNames declares entity names that entries may reference, for entities
+// already existing in the agent.
+func (b *EntityBuilder) KnownNames(names ...string) *EntityBuilder {
+	for _, n := range names {
+		b.known[n] = true
+	}
+	return b
+}
+
+// Enum makes entry values the patterns to match, as composite entities need.
+func (b *EntityBuilder) Enum() *EntityBuilder {
+	b.entity.IsEnum = true
+	return b
+}
+
+// Composite declares that entries reference other entities, which requires
+// an enum entity.
+func (b *EntityBuilder) Composite() *EntityBuilder {
+	b.composite = true
+	return b
+}
+
+func (b *EntityBuilder) AutomatedExpansion() *EntityBuilder {
+	b.entity.AutomatedExpansion = true
+	return b
+}
+
+// Entry adds an entry. Entries of mapping entities without synonyms use their
+// value as the only synonym.
+func (b *EntityBuilder) Entry(value string, synonyms ...string) *EntityBuilder {
+	if len(synonyms) == 0 {
+		synonyms = []string{value}
+	}
+	b.entity.Entries = append(b.entity.Entries, Entry{Value: value, Synonyms: synonyms})
+	return b
+}
+
+func (b *EntityBuilder) Build() (Entity, error) {
+	if b.entity.Name == "" {
+		return Entity{}, fmt.Errorf("apiai: entity name is empty")
+	}
+	if len(b.entity.Entries) == 0 {
+		return Entity{}, fmt.Errorf("apiai: entity %q has no entries", b.entity.Name)
+	}
+	if b.composite && !b.entity.IsEnum {
+		return Entity{}, fmt.Errorf("apiai: composite entity %q must be an enum", b.entity.Name)
+	}
+	refs, err := b.entity.References()
+	if err != nil {
+		return Entity{}, err
+	}
+	var unknown []string
+	seen := map[string]bool{}
+	for _, r := range refs {
+		if !r.System() && !b.known[r.Entity] && !seen[r.Entity] {
+			unknown = append(unknown, r.Entity)
+			seen[r.Entity] = true
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return Entity{}, fmt.Errorf("apiai: entity %q references unknown entities %s", b.entity.Name, strings.Join(unknown, ", "))
+	}
+	return b.entity, nil
+}
@@ -0,0 +1,108 @@
+package apiai
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEntityReferences(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		description      string
+		text             string
+		expectedResponse []EntityReference
+		expectedError    error
+	}{
+		{
+			description: "system and developer entities",
+			text:        "@sys.number:amount @currency",
+			expectedResponse: []EntityReference{
+				{Entity: "sys.number", Alias: "amount"},
+				{Entity: "currency"},
+			},
+		}, {
+			description:      "plain text and emails are not references",
+			text:             "write to sales@example.com",
+			expectedResponse: nil,
+		}, {
+			description:   "empty entity name",
+			text:          "pay @ 5",
+			expectedError: fmt.Errorf(`apiai: invalid entity reference "@" in "pay @ 5"`),
+		}, {
+			description:   "empty alias",
+			text:          "@sys.number: dollars",
+			expectedError: fmt.Errorf(`apiai: invalid alias in entity reference "@sys.number:" in "@sys.number: dollars"`),
+		}, {
+			description:   "duplicate alias",
+			text:          "from @city:city to @city:city",
+			expectedError: fmt.Errorf(`apiai: duplicate alias "city" in "from @city:city to @city:city"`),
+		},
+	}
+
+	for _, tc := range tests {
+		r, err := ParseEntityReferences(tc.text)
+
+		assert.Equal(tc.expectedResponse, r, tc.description)
+		assert.Equal(tc.expectedError, err, tc.description)
+	}
+}
+
+func TestResolveEntityDependencies(t *testing.T) {
+	assert := assert.New(t)
+
+	currency := Entity{Name: "currency", Entries: []Entry{{Value: "EUR", Synonyms: []string{"euro"}}}}
+	money := Entity{Name: "money", IsEnum: true, Entries: []Entry{{Value: "@sys.number:amount @currency:currency"}}}
+	prices := Entity{Name: "prices", IsEnum: true, Entries: []Entry{
+		{Value: "@money:money"},
+		{Value: "@money:from and @prices:more"},
+	}}
+
+	sorted, err := ResolveEntityDependencies([]Entity{prices, money, currency})
+	assert.Nil(err)
+	assert.Equal([]Entity{currency, money, prices}, sorted)
+
+	_, err = ResolveEntityDependencies([]Entity{prices, money})
+	assert.EqualError(err, `apiai: entity "money" references unknown entity "currency"`)
+
+	a := Entity{Name: "a", IsEnum: true, Entries: []Entry{{Value: "@b"}}}
+	b := Entity{Name: "b", IsEnum: true, Entries: []Entry{{Value: "@a"}}}
+	_, err = ResolveEntityDependencies([]Entity{a, b})
+	assert.EqualError(err, "apiai: circular entity references a -> b -> a")
+}
+
+func TestEntityBuilder(t *testing.T) {
+	assert := assert.New(t)
+	currency := Entity{Name: "currency"}
+
+	e, err := NewEntityBuilder("money").Enum().Known(currency).Entry("@sys.number:amount @currency:currency").Build()
+	assert.Nil(err)
+	assert.Equal(Entity{
+		Name:    "money",
+		IsEnum:  true,
+		Entries: []Entry{{Value: "@sys.number:amount @currency:currency", Synonyms: []string{"@sys.number:amount @currency:currency"}}},
+	}, e)
+	assert.True(e.IsComposite())
+
+	e, err = NewEntityBuilder("appliances").Entry("Kettle", "kettle", "boiler").Build()
+	assert.Nil(err)
+	assert.False(e.IsComposite())
+
+	_, err = NewEntityBuilder("money").Enum().Entry("@sys.number @currency @unit").Build()
+	assert.EqualError(err, `apiai: entity "money" references unknown entities currency, unit`)
+
+	_, err = NewEntityBuilder("money").KnownNames("currency").Composite().Entry("@sys.number @currency").Build()
+	assert.EqualError(err, `apiai: composite entity "money" must be an enum`)
+
+	e, err = NewEntityBuilder("handles").Entry("John", "John", "@john").Build()
+	assert.Nil(err, "synonyms of mapping entities aren't references")
+	assert.False(e.IsComposite())
+	sorted, err := ResolveEntityDependencies([]Entity{e})
+	assert.Nil(err)
+	assert.Equal([]Entity{e}, sorted)
+
+	_, err = NewEntityBuilder("money").Build()
+	assert.EqualError(err, `apiai: entity "money" has no entries`)
+}
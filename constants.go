@@ -1,3 +1,19 @@
 package apiai
 
+import "fmt"
+
 const DefaultErrorMsg = "apiai: wops something happens because status code is %v"
+
+// UnknownInputAction is the action of the default fallback intent, answered
+// when no intent matches.
+const UnknownInputAction = "input.unknown"
+
+// StatusError is returned when api.ai answers with an unexpected HTTP status.
+// Use errors.As to get it from errors wrapping it.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf(DefaultErrorMsg, e.StatusCode)
+}
@@ -2,7 +2,6 @@ package apiai
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"net/url"
 )
@@ -31,7 +30,7 @@ func (c *ApiClient) GetContexts(sessionId string) ([]Context, error) {
 		}
 		return contexts, nil
 	default:
-		return nil, fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 }
 
@@ -52,7 +51,7 @@ func (c *ApiClient) GetContext(name, sessionId string) (*Context, error) {
 		}
 		return context, nil
 	default:
-		return nil, fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 }
 
@@ -68,7 +67,7 @@ func (c *ApiClient) CreateContext(context Context, sessionId string) error {
 	case http.StatusOK:
 		return nil
 	default:
-		return fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return &StatusError{StatusCode: resp.StatusCode}
 	}
 }
 
@@ -83,7 +82,7 @@ func (c *ApiClient) DeleteContexts(sessionId string) error {
 	case http.StatusOK:
 		return nil
 	default:
-		return fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return &StatusError{StatusCode: resp.StatusCode}
 	}
 }
 
@@ -99,6 +98,6 @@ func (c *ApiClient) DeleteContext(name, sessionId string) error {
 	case http.StatusOK:
 		return nil
 	default:
-		return fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return &StatusError{StatusCode: resp.StatusCode}
 	}
 }
@@ -1,7 +1,6 @@
 package apiai
 
 import (
-	"fmt"
 	"net/http"
 	"net/url"
 	"testing"
@@ -70,7 +69,7 @@ func TestGetContexts(t *testing.T) {
 			description:      "api ai failed with an error 400",
 			responder:        httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
 			expectedResponse: nil,
-			expectedError:    fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError:    &StatusError{StatusCode: 400},
 		},
 	}
 
@@ -128,7 +127,7 @@ func TestGetContext(t *testing.T) {
 			description:      "api ai failed with an error 400",
 			responder:        httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
 			expectedResponse: nil,
-			expectedError:    fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError:    &StatusError{StatusCode: 400},
 		},
 	}
 
@@ -168,7 +167,7 @@ func TestCreateContext(t *testing.T) {
 		}, {
 			description:   "api ai failed with an error 400",
 			responder:     httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
-			expectedError: fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError: &StatusError{StatusCode: 400},
 		},
 	}
 
@@ -214,7 +213,7 @@ func TestDeleteContexts(t *testing.T) {
 		}, {
 			description:   "api ai failed with an error 400",
 			responder:     httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
-			expectedError: fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError: &StatusError{StatusCode: 400},
 		},
 	}
 
@@ -252,7 +251,7 @@ func TestDeleteContext(t *testing.T) {
 		}, {
 			description:   "api ai failed with an error 400",
 			responder:     httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
-			expectedError: fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError: &StatusError{StatusCode: 400},
 		},
 	}
 
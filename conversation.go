@@ -0,0 +1,153 @@
+package apiai
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+var conversationSessions int64
+
+// ConversationTurn is a user query, as text or event, and what the agent is
+// expected to answer. Speech is a regular expression and Contexts must be
+// active after the turn. Empty expectations are not checked.
+type ConversationTurn struct {
+	Say      string   `yaml:"say,omitempty"`
+	Event    string   `yaml:"event,omitempty"`
+	Intent   string   `yaml:"intent,omitempty"`
+	Action   string   `yaml:"action,omitempty"`
+	Speech   string   `yaml:"speech,omitempty"`
+	Contexts []string `yaml:"contexts,omitempty"`
+}
+
+// Conversation is a scripted dialog with an agent, written in YAML:
+//
+//	name: order a coffee
+//	turns:
+//	  - say: I want a coffee
+//	    action: order.create
+//	    speech: "(?i)which size"
+//	    contexts: [ordering]
+//	  - say: large
+//	    action: order.size
+type Conversation struct {
+	Name  string             `yaml:"name"`
+	Turns []ConversationTurn `yaml:"turns"`
+}
+
+func LoadConversation(r io.Reader) (*Conversation, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var c Conversation
+	if err := yaml.UnmarshalStrict(b, &c); err != nil {
+		return nil, fmt.Errorf("apiai: invalid conversation, %v", err)
+	}
+	for i, turn := range c.Turns {
+		if (turn.Say == "") == (turn.Event == "") {
+			return nil, fmt.Errorf("apiai: invalid conversation, turn %d must have either say or event", i+1)
+		}
+		if _, err := regexp.Compile(turn.Speech); err != nil {
+			return nil, fmt.Errorf("apiai: invalid conversation, turn %d speech, %v", i+1, err)
+		}
+	}
+	return &c, nil
+}
+
+func LoadConversationFile(path string) (*Conversation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadConversation(f)
+}
+
+// Write writes the conversation in YAML, as read by LoadConversation.
+func (c *Conversation) Write(w io.Writer) error {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// RecordTurn returns the turn of a query expecting the answer the agent gave,
+// to replay recorded conversations as tests.
+func RecordTurn(q Query, resp *QueryResponse) ConversationTurn {
+	turn := ConversationTurn{
+		Event:  q.Event.Name,
+		Intent: resp.Result.Metadata.IntentName,
+		Action: resp.Result.Action,
+	}
+	if len(q.Query) > 0 {
+		turn.Say = q.Query[0]
+	}
+	if resp.Result.Fulfillment.Speech != "" {
+		turn.Speech = "^" + regexp.QuoteMeta(resp.Result.Fulfillment.Speech) + "$"
+	}
+	for _, c := range resp.Result.Contexts {
+		turn.Contexts = append(turn.Contexts, c.Name)
+	}
+	return turn
+}
+
+// TurnResult is the answer of the agent to a turn and the expectations it
+// didn't meet.
+type TurnResult struct {
+	Turn     ConversationTurn
+	Response *QueryResponse
+	Failures []string
+}
+
+// Play sends the turns of the conversation in a new session, and stops on the
+// first query error.
+func (c *Conversation) Play(q Querier) ([]TurnResult, error) {
+	sessionId := fmt.Sprintf("conversation-%d-%d", time.Now().UnixNano(), atomic.AddInt64(&conversationSessions, 1))
+	var results []TurnResult
+	for i, turn := range c.Turns {
+		query := Query{SessionId: sessionId, ResetContexts: i == 0}
+		if turn.Event != "" {
+			query.Event = Event{Name: turn.Event}
+		} else {
+			query.Query = []string{turn.Say}
+		}
+		resp, err := q.Query(query)
+		if err != nil {
+			return results, fmt.Errorf("apiai: turn %d, %w", i+1, err)
+		}
+		results = append(results, TurnResult{Turn: turn, Response: resp, Failures: checkTurn(turn, resp)})
+	}
+	return results, nil
+}
+
+func checkTurn(turn ConversationTurn, resp *QueryResponse) []string {
+	var failures []string
+	if turn.Intent != "" && turn.Intent != resp.Result.Metadata.IntentName {
+		failures = append(failures, fmt.Sprintf("expected intent %q, got %q", turn.Intent, resp.Result.Metadata.IntentName))
+	}
+	if turn.Action != "" && turn.Action != resp.Result.Action {
+		failures = append(failures, fmt.Sprintf("expected action %q, got %q", turn.Action, resp.Result.Action))
+	}
+	if turn.Speech != "" {
+		if re, err := regexp.Compile(turn.Speech); err != nil {
+			failures = append(failures, fmt.Sprintf("invalid speech expectation, %v", err))
+		} else if !re.MatchString(resp.Result.Fulfillment.Speech) {
+			failures = append(failures, fmt.Sprintf("expected speech matching %q, got %q", turn.Speech, resp.Result.Fulfillment.Speech))
+		}
+	}
+	for _, name := range turn.Contexts {
+		if _, ok := FindContext(resp.Result.Contexts, name); !ok {
+			failures = append(failures, fmt.Sprintf("expected context %q to be active", name))
+		}
+	}
+	return failures
+}
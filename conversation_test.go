@@ -0,0 +1,114 @@
+package apiai
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// coffeeAgent answers like a small coffee ordering agent.
+var coffeeAgent = queryFunc(func(q Query) (*QueryResponse, error) {
+	resp := &QueryResponse{SessionId: q.SessionId}
+	switch {
+	case q.Event.Name == "WELCOME":
+		resp.Result = Result{Action: "input.welcome", Metadata: Metadata{IntentName: "welcome"},
+			Fulfillment: Fulfilment{Speech: "Hello! What would you like?"}}
+	case q.Query[0] == "I want a coffee":
+		resp.Result = Result{Action: "order.create", Metadata: Metadata{IntentName: "order"},
+			Fulfillment: Fulfilment{Speech: "Which size?"}, Contexts: []Context{{Name: "ordering", Lifespan: 2}}}
+	case q.Query[0] == "large":
+		resp.Result = Result{Action: "order.size", Metadata: Metadata{IntentName: "order.size"},
+			Fulfillment: Fulfilment{Speech: "Done"}}
+	default:
+		resp.Result = Result{Metadata: Metadata{IntentName: "order.fallback"},
+			Fulfillment: Fulfilment{Speech: "Small, medium or large?"}, Contexts: []Context{{Name: "ordering", Lifespan: 1}}}
+	}
+	return resp, nil
+})
+
+func TestConversation(t *testing.T) {
+	assert := assert.New(t)
+
+	c, err := LoadConversation(strings.NewReader(`
+name: order a coffee
+turns:
+  - event: WELCOME
+    speech: "^Hello"
+  - say: I want a coffee
+    intent: order
+    speech: "(?i)which size"
+    contexts: [ordering]
+  - say: large
+    action: order.size
+`))
+	assert.Nil(err)
+	results, err := c.Play(coffeeAgent)
+	assert.Nil(err)
+	assert.Len(results, 3)
+	for _, r := range results {
+		assert.Empty(r.Failures)
+	}
+
+	c.Turns[2].Say = "huge"
+	c.Turns[2].Contexts = []string{"paying"}
+	results, err = c.Play(coffeeAgent)
+	assert.Nil(err)
+	assert.Empty(results[1].Failures)
+	assert.Equal([]string{
+		`expected action "order.size", got ""`,
+		`expected context "paying" to be active`,
+	}, results[2].Failures)
+
+	results, err = c.Play(queryFunc(func(q Query) (*QueryResponse, error) {
+		return nil, fmt.Errorf("timeout")
+	}))
+	assert.Empty(results)
+	assert.EqualError(err, "apiai: turn 1, timeout")
+}
+
+func TestLoadConversationErrors(t *testing.T) {
+	_, err := LoadConversation(strings.NewReader("turns:\n  - say: hi\n    event: WELCOME\n"))
+	assert.EqualError(t, err, "apiai: invalid conversation, turn 1 must have either say or event")
+	_, err = LoadConversation(strings.NewReader("turns:\n  - say: hi\n    speech: \"(\"\n"))
+	assert.EqualError(t, err, "apiai: invalid conversation, turn 1 speech, error parsing regexp: missing closing ): `(`")
+}
+
+func TestRecordTurn(t *testing.T) {
+	assert := assert.New(t)
+
+	recorded := &Conversation{Name: "recorded"}
+	for _, q := range []Query{
+		{Event: Event{Name: "WELCOME"}, SessionId: "s1"},
+		{Query: []string{"I want a coffee"}, SessionId: "s1"},
+	} {
+		resp, _ := coffeeAgent.Query(q)
+		recorded.Turns = append(recorded.Turns, RecordTurn(q, resp))
+	}
+
+	buf := new(bytes.Buffer)
+	assert.Nil(recorded.Write(buf))
+	assert.Equal(`name: recorded
+turns:
+- event: WELCOME
+  intent: welcome
+  action: input.welcome
+  speech: ^Hello! What would you like\?$
+- say: I want a coffee
+  intent: order
+  action: order.create
+  speech: ^Which size\?$
+  contexts:
+  - ordering
+`, buf.String())
+
+	loaded, err := LoadConversation(buf)
+	assert.Nil(err)
+	results, err := loaded.Play(coffeeAgent)
+	assert.Nil(err)
+	for _, r := range results {
+		assert.Empty(r.Failures)
+	}
+}
@@ -2,7 +2,6 @@ package apiai
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 )
 
@@ -43,7 +42,7 @@ func (c *ApiClient) GetEntities() ([]EntityDescription, error) {
 		}
 		return entities, nil
 	default:
-		return nil, fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 }
 
@@ -64,7 +63,7 @@ func (c *ApiClient) GetEntity(idOrName string) (*Entity, error) {
 		}
 		return entity, nil
 	default:
-		return nil, fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 }
 
@@ -86,7 +85,7 @@ func (c *ApiClient) CreateEntity(entity Entity) (*CreationResponse, error) {
 		}
 		return cr, nil
 	default:
-		return nil, fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 }
 
@@ -102,7 +101,7 @@ func (c *ApiClient) AddEntries(idOrName string, entries []Entry) error {
 	case http.StatusOK:
 		return nil
 	default:
-		return fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return &StatusError{StatusCode: resp.StatusCode}
 	}
 }
 
@@ -118,7 +117,7 @@ func (c *ApiClient) UpdateEntities(entities []Entity) error {
 	case http.StatusOK:
 		return nil
 	default:
-		return fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return &StatusError{StatusCode: resp.StatusCode}
 	}
 }
 
@@ -134,7 +133,7 @@ func (c *ApiClient) UpdateEntity(idOrName string, entity Entity) error {
 	case http.StatusOK:
 		return nil
 	default:
-		return fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return &StatusError{StatusCode: resp.StatusCode}
 	}
 }
 
@@ -150,7 +149,7 @@ func (c *ApiClient) UpdateEntries(idOrName string, entries []Entry) error {
 	case http.StatusOK:
 		return nil
 	default:
-		return fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return &StatusError{StatusCode: resp.StatusCode}
 	}
 }
 
@@ -166,7 +165,7 @@ func (c *ApiClient) DeleteEntity(idOrName string) error {
 	case http.StatusOK:
 		return nil
 	default:
-		return fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return &StatusError{StatusCode: resp.StatusCode}
 	}
 }
 
@@ -182,6 +181,6 @@ func (c *ApiClient) DeleteEntries(idOrName string, entries []string) error {
 	case http.StatusOK:
 		return nil
 	default:
-		return fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return &StatusError{StatusCode: resp.StatusCode}
 	}
 }
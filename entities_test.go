@@ -1,7 +1,6 @@
 package apiai
 
 import (
-	"fmt"
 	"net/http"
 	"testing"
 
@@ -59,7 +58,7 @@ func TestGetEntities(t *testing.T) {
 			description:      "api ai failed with an error 400",
 			responder:        httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
 			expectedResponse: nil,
-			expectedError:    fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError:    &StatusError{StatusCode: 400},
 		},
 	}
 
@@ -136,7 +135,7 @@ func TestGetEntity(t *testing.T) {
 			description:      "api ai failed with an error 400",
 			responder:        httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
 			expectedResponse: nil,
-			expectedError:    fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError:    &StatusError{StatusCode: 400},
 		},
 	}
 
@@ -185,7 +184,7 @@ func TestCreateEntity(t *testing.T) {
 			description:      "api ai failed with an error 400",
 			responder:        httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
 			expectedResponse: nil,
-			expectedError:    fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError:    &StatusError{StatusCode: 400},
 		},
 	}
 
@@ -234,7 +233,7 @@ func TestAddEntries(t *testing.T) {
 		}, {
 			description:   "api ai failed with an error 400",
 			responder:     httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
-			expectedError: fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError: &StatusError{StatusCode: 400},
 		},
 	}
 
@@ -282,7 +281,7 @@ func TestUpdateEntities(t *testing.T) {
 		}, {
 			description:   "api ai failed with an error 400",
 			responder:     httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
-			expectedError: fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError: &StatusError{StatusCode: 400},
 		},
 	}
 
@@ -337,7 +336,7 @@ func TestUpdateEntity(t *testing.T) {
 		}, {
 			description:   "api ai failed with an error 400",
 			responder:     httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
-			expectedError: fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError: &StatusError{StatusCode: 400},
 		},
 	}
 
@@ -390,7 +389,7 @@ func TestUpdateEntries(t *testing.T) {
 		}, {
 			description:   "api ai failed with an error 400",
 			responder:     httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
-			expectedError: fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError: &StatusError{StatusCode: 400},
 		},
 	}
 
@@ -440,7 +439,7 @@ func TestDeleteEntity(t *testing.T) {
 		}, {
 			description:   "api ai failed with an error 400",
 			responder:     httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
-			expectedError: fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError: &StatusError{StatusCode: 400},
 		},
 	}
 
@@ -476,7 +475,7 @@ func TestDeleteEntries(t *testing.T) {
 		}, {
 			description:   "api ai failed with an error 400",
 			responder:     httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
-			expectedError: fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError: &StatusError{StatusCode: 400},
 		},
 	}
 
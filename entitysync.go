@@ -0,0 +1,147 @@
+package apiai
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+type EntryChange struct {
+	Value           string
+	AddedSynonyms   []string
+	RemovedSynonyms []string
+}
+
+// EntityPlan holds the entry operations needed to turn an entity into the
+// desired one.
+type EntityPlan struct {
+	Entity  string
+	Add     []Entry
+	Update  []Entry
+	Delete  []string
+	Changes []EntryChange
+}
+
+func (p *EntityPlan) Empty() bool {
+	return len(p.Add) == 0 && len(p.Update) == 0 && len(p.Delete) == 0
+}
+
+func (p *EntityPlan) String() string {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "entity %q: %d to add, %d to change, %d to delete\n", p.Entity, len(p.Add), len(p.Update), len(p.Delete))
+	for _, e := range p.Add {
+		fmt.Fprintf(buf, "  + %s (%s)\n", e.Value, strings.Join(e.Synonyms, ", "))
+	}
+	for _, c := range p.Changes {
+		var diff []string
+		for _, s := range c.AddedSynonyms {
+			diff = append(diff, "+"+s)
+		}
+		for _, s := range c.RemovedSynonyms {
+			diff = append(diff, "-"+s)
+		}
+		fmt.Fprintf(buf, "  ~ %s: %s\n", c.Value, strings.Join(diff, ", "))
+	}
+	for _, v := range p.Delete {
+		fmt.Fprintf(buf, "  - %s\n", v)
+	}
+	return buf.String()
+}
+
+// DiffEntries computes the plan to go from current to desired entries.
+// Entries are matched by value and synonyms are compared regardless of order.
+func DiffEntries(current, desired []Entry) *EntityPlan {
+	plan := &EntityPlan{}
+	existing := make(map[string]Entry, len(current))
+	for _, e := range current {
+		existing[e.Value] = e
+	}
+	wanted := make(map[string]bool, len(desired))
+	for _, e := range desired {
+		wanted[e.Value] = true
+		cur, ok := existing[e.Value]
+		if !ok {
+			plan.Add = append(plan.Add, e)
+			continue
+		}
+		added, removed := diffSynonyms(cur.Synonyms, e.Synonyms)
+		if len(added) > 0 || len(removed) > 0 {
+			plan.Update = append(plan.Update, e)
+			plan.Changes = append(plan.Changes, EntryChange{e.Value, added, removed})
+		}
+	}
+	for _, e := range current {
+		if !wanted[e.Value] {
+			plan.Delete = append(plan.Delete, e.Value)
+		}
+	}
+	return plan
+}
+
+func diffSynonyms(current, desired []string) (added, removed []string) {
+	cur := make(map[string]bool, len(current))
+	for _, s := range current {
+		cur[s] = true
+	}
+	des := make(map[string]bool, len(desired))
+	for _, s := range desired {
+		des[s] = true
+		if !cur[s] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range current {
+		if !des[s] {
+			removed = append(removed, s)
+		}
+	}
+	return added, removed
+}
+
+// PlanEntitySync fetches the current entity and returns the changes SyncEntity
+// would apply, without applying them.
+func (c *ApiClient) PlanEntitySync(desired Entity) (*EntityPlan, error) {
+	current, err := c.GetEntity(entityIdOrName(desired))
+	if err != nil {
+		return nil, err
+	}
+	plan := DiffEntries(current.Entries, desired.Entries)
+	plan.Entity = current.Name
+	return plan, nil
+}
+
+// SyncEntity updates the entries of an existing entity to match desired with
+// the minimal set of AddEntries, UpdateEntries and DeleteEntries calls.
+func (c *ApiClient) SyncEntity(desired Entity) (*EntityPlan, error) {
+	plan, err := c.PlanEntitySync(desired)
+	if err != nil {
+		return nil, err
+	}
+	return plan, c.ApplyEntityPlan(entityIdOrName(desired), plan)
+}
+
+func (c *ApiClient) ApplyEntityPlan(idOrName string, plan *EntityPlan) error {
+	if len(plan.Delete) > 0 {
+		if err := c.DeleteEntries(idOrName, plan.Delete); err != nil {
+			return err
+		}
+	}
+	if len(plan.Update) > 0 {
+		if err := c.UpdateEntries(idOrName, plan.Update); err != nil {
+			return err
+		}
+	}
+	if len(plan.Add) > 0 {
+		if err := c.AddEntries(idOrName, plan.Add); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func entityIdOrName(e Entity) string {
+	if e.Id != "" {
+		return e.Id
+	}
+	return e.Name
+}
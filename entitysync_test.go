@@ -0,0 +1,102 @@
+package apiai
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffEntries(t *testing.T) {
+	assert := assert.New(t)
+
+	current := []Entry{
+		{Value: "Coffee Maker", Synonyms: []string{"coffee maker", "java"}},
+		{Value: "Kettle", Synonyms: []string{"kettle", "boiler"}},
+		{Value: "Toaster", Synonyms: []string{"toaster"}},
+	}
+	desired := []Entry{
+		{Value: "Coffee Maker", Synonyms: []string{"coffee maker", "coffee machine"}},
+		{Value: "Kettle", Synonyms: []string{"boiler", "kettle"}},
+		{Value: "Fridge", Synonyms: []string{"fridge"}},
+	}
+
+	plan := DiffEntries(current, desired)
+	plan.Entity = "Appliances"
+
+	assert.Equal(&EntityPlan{
+		Entity: "Appliances",
+		Add:    []Entry{{Value: "Fridge", Synonyms: []string{"fridge"}}},
+		Update: []Entry{{Value: "Coffee Maker", Synonyms: []string{"coffee maker", "coffee machine"}}},
+		Delete: []string{"Toaster"},
+		Changes: []EntryChange{
+			{Value: "Coffee Maker", AddedSynonyms: []string{"coffee machine"}, RemovedSynonyms: []string{"java"}},
+		},
+	}, plan)
+	assert.Equal(`entity "Appliances": 1 to add, 1 to change, 1 to delete
+  + Fridge (fridge)
+  ~ Coffee Maker: +coffee machine, -java
+  - Toaster
+`, plan.String())
+
+	assert.True(DiffEntries(current, current).Empty())
+}
+
+func TestSyncEntity(t *testing.T) {
+	c, err := NewClient(&ClientConfig{Token: "fakeToken"})
+	if err != nil {
+		t.FailNow()
+	}
+	assert := assert.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var calls []string
+	recorder := func(req *http.Request) (*http.Response, error) {
+		b, _ := ioutil.ReadAll(req.Body)
+		var body interface{}
+		json.Unmarshal(b, &body)
+		calls = append(calls, fmt.Sprintf("%s %v", req.Method, body))
+		return httpmock.NewStringResponse(200, `{}`), nil
+	}
+	httpmock.RegisterResponder("GET", c.buildUrl("entities/Appliances", nil), httpmock.NewStringResponder(200, `{
+  "id": "33868522-5747-4a31-88fb-3cd13bd18684",
+  "name": "Appliances",
+  "entries": [
+    {"value": "Kettle", "synonyms": ["kettle"]},
+    {"value": "Toaster", "synonyms": ["toaster"]}
+  ]
+}`))
+	httpmock.RegisterResponder("POST", c.buildUrl("entities/Appliances/entries", nil), recorder)
+	httpmock.RegisterResponder("PUT", c.buildUrl("entities/Appliances/entries", nil), recorder)
+	httpmock.RegisterResponder("DELETE", c.buildUrl("entities/Appliances/entries", nil), recorder)
+
+	desired := Entity{
+		Name: "Appliances",
+		Entries: []Entry{
+			{Value: "Kettle", Synonyms: []string{"kettle"}},
+			{Value: "Fridge", Synonyms: []string{"fridge"}},
+		},
+	}
+
+	plan, err := c.PlanEntitySync(desired)
+	assert.Nil(err)
+	assert.Equal([]string{"Toaster"}, plan.Delete)
+	assert.Empty(calls, "planning does not apply changes")
+
+	plan, err = c.SyncEntity(desired)
+	assert.Nil(err)
+	assert.Len(plan.Add, 1)
+	assert.Equal([]string{
+		"DELETE [Toaster]",
+		"POST [map[synonyms:[fridge] value:Fridge]]",
+	}, calls)
+
+	httpmock.RegisterResponder("GET", c.buildUrl("entities/Appliances", nil), httpmock.NewStringResponder(http.StatusNotFound, `{}`))
+	_, err = c.SyncEntity(desired)
+	assert.Equal(&StatusError{StatusCode: 404}, err)
+}
@@ -0,0 +1,259 @@
+package apiai
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// EntryError reports an invalid entry together with the line where it was found.
+type EntryError struct {
+	Line int
+	Msg  string
+}
+
+func (e *EntryError) Error() string {
+	return fmt.Sprintf("apiai: line %d: %s", e.Line, e.Msg)
+}
+
+// EntryErrors groups every validation error found by ReadEntries.
+type EntryErrors []*EntryError
+
+func (e EntryErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// EntryReader reads entries one at a time so large files don't have to be
+// loaded in memory. Read returns io.EOF when there are no more entries, and an
+// *EntryError for invalid entries, after which reading can continue.
+type EntryReader interface {
+	Read() (Entry, error)
+}
+
+// ReadEntries reads all the entries of r. Invalid entries are skipped and
+// returned as EntryErrors along with the valid ones.
+func ReadEntries(r EntryReader) ([]Entry, error) {
+	var entries []Entry
+	var errs EntryErrors
+	for {
+		entry, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if e, ok := err.(*EntryError); ok {
+				errs = append(errs, e)
+				continue
+			}
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if len(errs) > 0 {
+		return entries, errs
+	}
+	return entries, nil
+}
+
+type entryValidator struct {
+	values map[string]int
+}
+
+func newEntryValidator() *entryValidator {
+	return &entryValidator{values: map[string]int{}}
+}
+
+func (v *entryValidator) validate(entry Entry, line int) error {
+	if strings.TrimSpace(entry.Value) == "" {
+		return &EntryError{line, "entry value is empty"}
+	}
+	if prev, ok := v.values[entry.Value]; ok {
+		return &EntryError{line, fmt.Sprintf("duplicate entry %q, first defined at line %d", entry.Value, prev)}
+	}
+	synonyms := map[string]bool{}
+	for _, s := range entry.Synonyms {
+		if strings.TrimSpace(s) == "" {
+			return &EntryError{line, fmt.Sprintf("entry %q has an empty synonym", entry.Value)}
+		}
+		if synonyms[s] {
+			return &EntryError{line, fmt.Sprintf("entry %q has duplicate synonym %q", entry.Value, s)}
+		}
+		synonyms[s] = true
+	}
+	v.values[entry.Value] = line
+	return nil
+}
+
+// CSVEntryReader reads entries in the api.ai CSV format, one entry per row
+// with the reference value followed by its synonyms.
+type CSVEntryReader struct {
+	r         *csv.Reader
+	validator *entryValidator
+}
+
+func NewCSVEntryReader(r io.Reader) *CSVEntryReader {
+	br := bufio.NewReader(r)
+	// Spreadsheet tools often prepend a byte order mark.
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\ufeff" {
+		br.Discard(3)
+	}
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	return &CSVEntryReader{r: cr, validator: newEntryValidator()}
+}
+
+func (r *CSVEntryReader) Read() (Entry, error) {
+	record, err := r.r.Read()
+	if err != nil {
+		if pe, ok := err.(*csv.ParseError); ok {
+			return Entry{}, &EntryError{pe.Line, pe.Err.Error()}
+		}
+		return Entry{}, err
+	}
+	line, _ := r.r.FieldPos(0)
+
+	entry := Entry{Value: record[0], Synonyms: record[1:]}
+	if len(entry.Synonyms) == 0 {
+		entry.Synonyms = []string{entry.Value}
+	}
+	if err := r.validator.validate(entry, line); err != nil {
+		return Entry{}, err
+	}
+	return entry, nil
+}
+
+// JSONEntryReader reads entries from a JSON array of entries, the format used
+// for entries files in api.ai agent exports.
+type JSONEntryReader struct {
+	d         *json.Decoder
+	lines     *lineTracker
+	validator *entryValidator
+	started   bool
+	done      bool
+}
+
+func NewJSONEntryReader(r io.Reader) *JSONEntryReader {
+	lines := &lineTracker{r: r, line: 1}
+	return &JSONEntryReader{d: json.NewDecoder(lines), lines: lines, validator: newEntryValidator()}
+}
+
+func (r *JSONEntryReader) Read() (Entry, error) {
+	if r.done {
+		return Entry{}, io.EOF
+	}
+	if !r.started {
+		tok, err := r.d.Token()
+		if err != nil {
+			return Entry{}, r.wrap(err)
+		}
+		if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+			r.done = true
+			return Entry{}, &EntryError{r.lines.lineAt(r.d.InputOffset()), "expected an array of entries"}
+		}
+		r.started = true
+	}
+	if !r.d.More() {
+		if _, err := r.d.Token(); err != nil {
+			return Entry{}, r.wrap(err)
+		}
+		r.done = true
+		return Entry{}, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := r.d.Decode(&raw); err != nil {
+		return Entry{}, r.wrap(err)
+	}
+	line := r.lines.lineAt(r.d.InputOffset() - int64(len(raw)))
+	var entry Entry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return Entry{}, &EntryError{line, err.Error()}
+	}
+	if err := r.validator.validate(entry, line); err != nil {
+		return Entry{}, err
+	}
+	return entry, nil
+}
+
+// wrap converts decoding errors, after which the input can't be read any
+// further, into an EntryError.
+func (r *JSONEntryReader) wrap(err error) error {
+	r.done = true
+	if err == io.EOF {
+		return &EntryError{r.lines.lineAt(r.d.InputOffset()), "unexpected end of input"}
+	}
+	if se, ok := err.(*json.SyntaxError); ok {
+		return &EntryError{r.lines.lineAt(se.Offset), se.Error()}
+	}
+	return err
+}
+
+// lineTracker maps input offsets to line numbers. Offsets must be requested
+// in increasing order, so only newlines not yet passed are kept in memory.
+type lineTracker struct {
+	r        io.Reader
+	read     int64
+	newlines []int64
+	line     int
+}
+
+func (t *lineTracker) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] == '\n' {
+			t.newlines = append(t.newlines, t.read+int64(i))
+		}
+	}
+	t.read += int64(n)
+	return n, err
+}
+
+func (t *lineTracker) lineAt(offset int64) int {
+	for len(t.newlines) > 0 && t.newlines[0] < offset {
+		t.newlines = t.newlines[1:]
+		t.line++
+	}
+	return t.line
+}
+
+// WriteEntriesCSV writes entries in the api.ai CSV format.
+func WriteEntriesCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	for _, entry := range entries {
+		if err := cw.Write(append([]string{entry.Value}, entry.Synonyms...)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteEntriesJSON writes entries as a JSON array, one entry per line.
+func WriteEntriesJSON(w io.Writer, entries []Entry) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for i, entry := range entries {
+		b, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		sep := ",\n  "
+		if i == 0 {
+			sep = "\n  "
+		}
+		if _, err := io.WriteString(w, sep+string(b)); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "\n]\n")
+	return err
+}
@@ -0,0 +1,133 @@
+package apiai
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadEntriesCSV(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		description      string
+		input            string
+		expectedResponse []Entry
+		expectedError    error
+	}{
+		{
+			description: "valid entries",
+			input: "\ufeff\"Coffee Maker\",\"Coffee Maker\",\"coffee machine\"\n" +
+				"Kettle\n" +
+				"\"Toaster\", \"toaster\", \"bread, toaster\"\n",
+			expectedResponse: []Entry{
+				{Value: "Coffee Maker", Synonyms: []string{"Coffee Maker", "coffee machine"}},
+				{Value: "Kettle", Synonyms: []string{"Kettle"}},
+				{Value: "Toaster", Synonyms: []string{"toaster", "bread, toaster"}},
+			},
+			expectedError: nil,
+		}, {
+			description: "duplicates and empty values are reported with their line",
+			input: "Kettle,kettle\n" +
+				",empty\n" +
+				"Toaster,toaster,toaster\n" +
+				"Kettle,boiler\n" +
+				"Fridge,fridge\n",
+			expectedResponse: []Entry{
+				{Value: "Kettle", Synonyms: []string{"kettle"}},
+				{Value: "Fridge", Synonyms: []string{"fridge"}},
+			},
+			expectedError: EntryErrors{
+				{Line: 2, Msg: "entry value is empty"},
+				{Line: 3, Msg: `entry "Toaster" has duplicate synonym "toaster"`},
+				{Line: 4, Msg: `duplicate entry "Kettle", first defined at line 1`},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		r, err := ReadEntries(NewCSVEntryReader(strings.NewReader(tc.input)))
+
+		assert.Equal(tc.expectedResponse, r, tc.description)
+		assert.Equal(tc.expectedError, err, tc.description)
+	}
+}
+
+func TestReadEntriesJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		description      string
+		input            string
+		expectedResponse []Entry
+		expectedError    error
+	}{
+		{
+			description: "valid entries",
+			input: `[
+  {"value": "Coffee Maker", "synonyms": ["Coffee Maker", "coffee machine"]},
+  {
+    "value": "Kettle",
+    "synonyms": ["Kettle"]
+  }
+]`,
+			expectedResponse: []Entry{
+				{Value: "Coffee Maker", Synonyms: []string{"Coffee Maker", "coffee machine"}},
+				{Value: "Kettle", Synonyms: []string{"Kettle"}},
+			},
+			expectedError: nil,
+		}, {
+			description: "invalid entries are reported with their line",
+			input: `[
+  {"value": "Kettle", "synonyms": ["Kettle"]},
+  {"value": "", "synonyms": []},
+
+  {"value": "Kettle", "synonyms": ["boiler"]},
+  {"value": 3, "synonyms": []}
+]`,
+			expectedResponse: []Entry{
+				{Value: "Kettle", Synonyms: []string{"Kettle"}},
+			},
+			expectedError: EntryErrors{
+				{Line: 3, Msg: "entry value is empty"},
+				{Line: 5, Msg: `duplicate entry "Kettle", first defined at line 2`},
+				{Line: 6, Msg: "json: cannot unmarshal number into Go struct field Entry.value of type string"},
+			},
+		}, {
+			description:      "not an array",
+			input:            "\n{}",
+			expectedResponse: nil,
+			expectedError:    EntryErrors{{Line: 2, Msg: "expected an array of entries"}},
+		},
+	}
+
+	for _, tc := range tests {
+		r, err := ReadEntries(NewJSONEntryReader(strings.NewReader(tc.input)))
+
+		assert.Equal(tc.expectedResponse, r, tc.description)
+		assert.Equal(tc.expectedError, err, tc.description)
+	}
+}
+
+func TestWriteEntries(t *testing.T) {
+	assert := assert.New(t)
+	entries := []Entry{
+		{Value: "Coffee Maker", Synonyms: []string{"Coffee Maker", "coffee, machine"}},
+		{Value: "Kettle", Synonyms: []string{"Kettle"}},
+	}
+
+	csv := new(bytes.Buffer)
+	assert.Nil(WriteEntriesCSV(csv, entries))
+	assert.Equal("Coffee Maker,Coffee Maker,\"coffee, machine\"\nKettle,Kettle\n", csv.String())
+	r, err := ReadEntries(NewCSVEntryReader(csv))
+	assert.Nil(err)
+	assert.Equal(entries, r)
+
+	json := new(bytes.Buffer)
+	assert.Nil(WriteEntriesJSON(json, entries))
+	r, err = ReadEntries(NewJSONEntryReader(json))
+	assert.Nil(err)
+	assert.Equal(entries, r)
+}
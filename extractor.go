@@ -0,0 +1,198 @@
+package apiai
+
+import (
+	"strings"
+	"unicode"
+)
+
+// EntitySpan is an entity value found in a text. Start and End are byte
+// offsets of Text within the original text.
+type EntitySpan struct {
+	Entity string
+	Value  string
+	Text   string
+	Start  int
+	End    int
+}
+
+type matchNode struct {
+	children map[string]*matchNode
+	entity   string
+	value    string
+	terminal bool
+}
+
+// EntityMatcher finds entity values in texts locally, matching the synonyms of
+// the entities it has been compiled from regardless of case and diacritics.
+type EntityMatcher struct {
+	root *matchNode
+}
+
+// NewEntityMatcher compiles a matcher from entities. When a synonym belongs to
+// several entries, the first one in entities wins. Composite entries are
+// ignored since they can't be matched literally.
+func NewEntityMatcher(entities []Entity) *EntityMatcher {
+	m := &EntityMatcher{root: &matchNode{}}
+	for _, entity := range entities {
+		for _, entry := range entity.Entries {
+			synonyms := entry.Synonyms
+			if len(synonyms) == 0 {
+				synonyms = []string{entry.Value}
+			}
+			for _, s := range synonyms {
+				if strings.Contains(s, "@") {
+					continue
+				}
+				m.add(s, entity.Name, entry.Value)
+			}
+		}
+	}
+	return m
+}
+
+func (m *EntityMatcher) add(synonym, entity, value string) {
+	tokens := tokenize(synonym)
+	if len(tokens) == 0 {
+		return
+	}
+	node := m.root
+	for _, t := range tokens {
+		if node.children == nil {
+			node.children = map[string]*matchNode{}
+		}
+		next, ok := node.children[t.text]
+		if !ok {
+			next = &matchNode{}
+			node.children[t.text] = next
+		}
+		node = next
+	}
+	if !node.terminal {
+		node.terminal, node.entity, node.value = true, entity, value
+	}
+}
+
+// Match returns the non overlapping spans of text matching an entity,
+// preferring the longest match at each position.
+func (m *EntityMatcher) Match(text string) []EntitySpan {
+	tokens := tokenize(text)
+	var spans []EntitySpan
+	for i := 0; i < len(tokens); {
+		node := m.root
+		matched := -1
+		var match *matchNode
+		for j := i; j < len(tokens); j++ {
+			node = node.children[tokens[j].text]
+			if node == nil {
+				break
+			}
+			if node.terminal {
+				matched, match = j, node
+			}
+		}
+		if matched < 0 {
+			i++
+			continue
+		}
+		start, end := tokens[i].start, tokens[matched].end
+		spans = append(spans, EntitySpan{
+			Entity: match.entity,
+			Value:  match.value,
+			Text:   text[start:end],
+			Start:  start,
+			End:    end,
+		})
+		i = matched + 1
+	}
+	return spans
+}
+
+// AnnotateQuery matches the texts of q and adds the matched values as session
+// entities extending the agent ones, with the exact text found as synonym, so
+// api.ai recognizes values that only matched locally after folding. Matches
+// are merged into the session entities q already has with the same name.
+func (m *EntityMatcher) AnnotateQuery(q Query) (Query, []EntitySpan) {
+	var spans []EntitySpan
+	entities := map[string]int{}
+	entries := map[[2]string]int{}
+	q.Entities = append([]UserEntity(nil), q.Entities...)
+	for _, text := range q.Query {
+		for _, span := range m.Match(text) {
+			spans = append(spans, span)
+			i, ok := entities[span.Entity]
+			if !ok {
+				i = indexUserEntity(q.Entities, span.Entity)
+				if i < 0 {
+					i = len(q.Entities)
+					q.Entities = append(q.Entities, UserEntity{Name: span.Entity, Extend: true})
+				}
+				entities[span.Entity] = i
+				// Copy the entries so the caller's query is left untouched.
+				q.Entities[i].Entries = append([]Entry(nil), q.Entities[i].Entries...)
+				for j, e := range q.Entities[i].Entries {
+					key := [2]string{span.Entity, e.Value}
+					if _, ok := entries[key]; !ok {
+						entries[key] = j
+					}
+				}
+			}
+			entity := &q.Entities[i]
+			key := [2]string{span.Entity, span.Value}
+			j, ok := entries[key]
+			if !ok {
+				j = len(entity.Entries)
+				entries[key] = j
+				entity.Entries = append(entity.Entries, Entry{Value: span.Value, Synonyms: []string{span.Value}})
+			}
+			synonyms := entity.Entries[j].Synonyms
+			entity.Entries[j].Synonyms = uniqueStrings(append(synonyms[:len(synonyms):len(synonyms)], span.Text))
+		}
+	}
+	return q, spans
+}
+
+func indexUserEntity(entities []UserEntity, name string) int {
+	for i, e := range entities {
+		if e.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+type token struct {
+	text       string
+	start, end int
+}
+
+// tokenize splits s in words of letters and digits, folding their case and
+// diacritics.
+func tokenize(s string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range s {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		if word && start < 0 {
+			start = i
+		}
+		if !word && start >= 0 {
+			tokens = append(tokens, token{foldToken(s[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{foldToken(s[start:]), start, len(s)})
+	}
+	return tokens
+}
+
+func foldToken(s string) string {
+	s = strings.ToLower(StripDiacritics(s))
+	// Drop combining marks left by decomposed accents.
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
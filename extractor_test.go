@@ -0,0 +1,91 @@
+package apiai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntityMatcher(t *testing.T) {
+	assert := assert.New(t)
+	m := NewEntityMatcher([]Entity{
+		{Name: "drink", Entries: []Entry{
+			{Value: "coffee", Synonyms: []string{"coffee", "café"}},
+			{Value: "iced coffee", Synonyms: []string{"iced coffee", "frappé"}},
+			{Value: "tea", Synonyms: []string{"tea"}},
+		}},
+		{Name: "size", Entries: []Entry{
+			{Value: "large", Synonyms: []string{"large", "big"}},
+			{Value: "tea", Synonyms: []string{"tea"}},
+		}},
+		{Name: "order", IsEnum: true, Entries: []Entry{
+			{Value: "@size:size @drink:drink", Synonyms: []string{"@size:size @drink:drink"}},
+		}},
+	})
+
+	tests := []struct {
+		description      string
+		text             string
+		expectedResponse []EntitySpan
+	}{
+		{
+			description: "longest match, case and diacritics folded",
+			text:        "A BIG Iced Coffee and a cafe, please",
+			expectedResponse: []EntitySpan{
+				{Entity: "size", Value: "large", Text: "BIG", Start: 2, End: 5},
+				{Entity: "drink", Value: "iced coffee", Text: "Iced Coffee", Start: 6, End: 17},
+				{Entity: "drink", Value: "coffee", Text: "cafe", Start: 24, End: 28},
+			},
+		}, {
+			description: "only whole words match, first entity wins",
+			text:        "teapot or tea?",
+			expectedResponse: []EntitySpan{
+				{Entity: "drink", Value: "tea", Text: "tea", Start: 10, End: 13},
+			},
+		}, {
+			description:      "no match",
+			text:             "hello there",
+			expectedResponse: nil,
+		},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(tc.expectedResponse, m.Match(tc.text), tc.description)
+	}
+}
+
+func TestEntityMatcherAnnotateQuery(t *testing.T) {
+	assert := assert.New(t)
+	m := NewEntityMatcher([]Entity{
+		{Name: "drink", Entries: []Entry{{Value: "coffee", Synonyms: []string{"coffee", "café"}}}},
+	})
+
+	q, spans := m.AnnotateQuery(Query{Query: []string{"one Cafe", "two cafe and a coffee"}, SessionId: "123454321"})
+
+	assert.Len(spans, 3)
+	assert.Equal([]UserEntity{
+		{Name: "drink", Extend: true, Entries: []Entry{
+			{Value: "coffee", Synonyms: []string{"coffee", "Cafe", "cafe"}},
+		}},
+	}, q.Entities)
+	original := Query{
+		Query: []string{"a cafe please"},
+		Entities: []UserEntity{
+			{Name: "size", Entries: []Entry{{Value: "large", Synonyms: []string{"large"}}}},
+			{Name: "drink", Entries: []Entry{
+				{Value: "tea", Synonyms: []string{"tea"}},
+				{Value: "coffee", Synonyms: []string{"coffee", "espresso"}},
+			}},
+		},
+	}
+	q, _ = m.AnnotateQuery(original)
+
+	assert.Equal([]UserEntity{
+		{Name: "size", Entries: []Entry{{Value: "large", Synonyms: []string{"large"}}}},
+		{Name: "drink", Entries: []Entry{
+			{Value: "tea", Synonyms: []string{"tea"}},
+			{Value: "coffee", Synonyms: []string{"coffee", "espresso", "cafe"}},
+		}},
+	}, q.Entities, "matches are merged into the existing session entity")
+	assert.Equal([]string{"coffee", "espresso"}, original.Entities[1].Entries[1].Synonyms)
+}
@@ -0,0 +1,156 @@
+package apiai
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FlowNode is an intent in a conversation flow graph.
+type FlowNode struct {
+	Intent   string
+	Contexts []string
+	Events   []string
+	Fallback bool
+}
+
+// FlowEdge connects an intent that outputs Context to an intent that takes it
+// as input.
+type FlowEdge struct {
+	From    string
+	To      string
+	Context string
+}
+
+// FlowGraph is the conversation flow defined by the input and output contexts
+// of the intents of an agent.
+type FlowGraph struct {
+	Nodes []FlowNode
+	Edges []FlowEdge
+}
+
+// NewFlowGraph builds the flow graph of intents. Contexts are matched case
+// insensitively and the ones that are reset, with a lifespan of 0, don't
+// create edges.
+func NewFlowGraph(intents []Intent) *FlowGraph {
+	g := &FlowGraph{}
+	consumers := map[string][]string{}
+	for _, intent := range intents {
+		g.Nodes = append(g.Nodes, FlowNode{
+			Intent:   intent.Name,
+			Contexts: sortedStrings(intent.Contexts),
+			Events:   sortedStrings(eventNames(intent.Events)),
+			Fallback: intent.FallbackIntent,
+		})
+		for _, c := range intent.Contexts {
+			consumers[strings.ToLower(c)] = append(consumers[strings.ToLower(c)], intent.Name)
+		}
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].Intent < g.Nodes[j].Intent })
+
+	seen := map[FlowEdge]bool{}
+	for _, intent := range intents {
+		for _, r := range intent.Responses {
+			for _, c := range r.AffectedContexts {
+				if c.Lifespan <= 0 {
+					continue
+				}
+				for _, to := range consumers[strings.ToLower(c.Name)] {
+					e := FlowEdge{From: intent.Name, To: to, Context: c.Name}
+					if !seen[e] {
+						seen[e] = true
+						g.Edges = append(g.Edges, e)
+					}
+				}
+			}
+		}
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Context < b.Context
+	})
+	return g
+}
+
+func (g *FlowGraph) events() []string {
+	var events []string
+	for _, n := range g.Nodes {
+		events = append(events, n.Events...)
+	}
+	return uniqueStrings(sortedStrings(events))
+}
+
+// DOT renders the graph in the Graphviz DOT language. Events are drawn as
+// ellipses pointing to the intents they trigger and fallback intents are
+// dashed.
+func (g *FlowGraph) DOT() string {
+	buf := new(bytes.Buffer)
+	buf.WriteString("digraph conversation {\n")
+	buf.WriteString("  rankdir=LR;\n")
+	buf.WriteString("  node [shape=box];\n")
+	for _, e := range g.events() {
+		fmt.Fprintf(buf, "  %q [shape=ellipse, label=%q];\n", "event:"+e, e)
+	}
+	for _, n := range g.Nodes {
+		if n.Fallback {
+			fmt.Fprintf(buf, "  %q [style=dashed];\n", n.Intent)
+		} else {
+			fmt.Fprintf(buf, "  %q;\n", n.Intent)
+		}
+	}
+	for _, n := range g.Nodes {
+		for _, e := range n.Events {
+			fmt.Fprintf(buf, "  %q -> %q [style=dotted];\n", "event:"+e, n.Intent)
+		}
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(buf, "  %q -> %q [label=%q];\n", e.From, e.To, e.Context)
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart, which can be embedded in
+// Markdown.
+func (g *FlowGraph) Mermaid() string {
+	ids := map[string]string{}
+	buf := new(bytes.Buffer)
+	buf.WriteString("flowchart LR\n")
+	for i, e := range g.events() {
+		ids["event:"+e] = fmt.Sprintf("e%d", i)
+		fmt.Fprintf(buf, "  e%d([\"%s\"])\n", i, mermaidLabel(e))
+	}
+	var fallbacks []string
+	for i, n := range g.Nodes {
+		id := fmt.Sprintf("i%d", i)
+		ids[n.Intent] = id
+		fmt.Fprintf(buf, "  %s[\"%s\"]\n", id, mermaidLabel(n.Intent))
+		if n.Fallback {
+			fallbacks = append(fallbacks, id)
+		}
+	}
+	for _, n := range g.Nodes {
+		for _, e := range n.Events {
+			fmt.Fprintf(buf, "  %s -.-> %s\n", ids["event:"+e], ids[n.Intent])
+		}
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(buf, "  %s -->|\"%s\"| %s\n", ids[e.From], mermaidLabel(e.Context), ids[e.To])
+	}
+	if len(fallbacks) > 0 {
+		buf.WriteString("  classDef fallback stroke-dasharray: 5 5\n")
+		fmt.Fprintf(buf, "  class %s fallback\n", strings.Join(fallbacks, ","))
+	}
+	return buf.String()
+}
+
+func mermaidLabel(s string) string {
+	return strings.Replace(s, `"`, "#quot;", -1)
+}
@@ -0,0 +1,56 @@
+package apiai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlowGraph(t *testing.T) {
+	assert := assert.New(t)
+
+	g := NewFlowGraph([]Intent{
+		{Name: "order", Responses: []IntentResponse{{AffectedContexts: []Context{{Name: "Ordering", Lifespan: 2}}}}},
+		{Name: "welcome", Events: []Event{{Name: "WELCOME"}}, Responses: []IntentResponse{{AffectedContexts: []Context{{Name: "ordering", Lifespan: 5}}}}},
+		{Name: "confirm", Contexts: []string{"ordering"}, Responses: []IntentResponse{{AffectedContexts: []Context{{Name: "ordering", Lifespan: 0}}}}},
+		{Name: "fallback \"ordering\"", Contexts: []string{"ordering"}, FallbackIntent: true},
+	})
+
+	assert.Equal([]FlowEdge{
+		{From: "order", To: "confirm", Context: "Ordering"},
+		{From: "order", To: "fallback \"ordering\"", Context: "Ordering"},
+		{From: "welcome", To: "confirm", Context: "ordering"},
+		{From: "welcome", To: "fallback \"ordering\"", Context: "ordering"},
+	}, g.Edges)
+
+	assert.Equal(`digraph conversation {
+  rankdir=LR;
+  node [shape=box];
+  "event:WELCOME" [shape=ellipse, label="WELCOME"];
+  "confirm";
+  "fallback \"ordering\"" [style=dashed];
+  "order";
+  "welcome";
+  "event:WELCOME" -> "welcome" [style=dotted];
+  "order" -> "confirm" [label="Ordering"];
+  "order" -> "fallback \"ordering\"" [label="Ordering"];
+  "welcome" -> "confirm" [label="ordering"];
+  "welcome" -> "fallback \"ordering\"" [label="ordering"];
+}
+`, g.DOT())
+
+	assert.Equal(`flowchart LR
+  e0(["WELCOME"])
+  i0["confirm"]
+  i1["fallback #quot;ordering#quot;"]
+  i2["order"]
+  i3["welcome"]
+  e0 -.-> i3
+  i2 -->|"Ordering"| i0
+  i2 -->|"Ordering"| i1
+  i3 -->|"ordering"| i0
+  i3 -->|"ordering"| i1
+  classDef fallback stroke-dasharray: 5 5
+  class i1 fallback
+`, g.Mermaid())
+}
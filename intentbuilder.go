@@ -0,0 +1,256 @@
+package apiai
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// phraseAnnotation matches example annotations like [5](@sys.number:guests)
+// and template references like @sys.number:guests.
+var phraseAnnotation = regexp.MustCompile(`\[([^\]]*)\]\(@([\w.-]+)(?::([\w-]+))?\)|@([\w.-]+)(?::([\w-]+))?`)
+
+// ParseUserSays parses an annotated phrase into UserSays data chunks. Phrases
+// may reference entities as templates, "book a table for @sys.number:guests",
+// or annotate example values, "book a table for [5](@sys.number:guests)".
+// Mixing both styles in the same phrase is an error. References without alias
+// use the entity name, without the sys. prefix, as alias.
+func ParseUserSays(phrase string) (UserSays, error) {
+	us := UserSays{}
+	template, example := false, false
+	last := 0
+	for _, m := range phraseAnnotation.FindAllStringSubmatchIndex(phrase, -1) {
+		var text, entity, alias string
+		if m[2] >= 0 {
+			text, entity = phrase[m[2]:m[3]], phrase[m[4]:m[5]]
+			if m[6] >= 0 {
+				alias = phrase[m[6]:m[7]]
+			}
+			if text == "" {
+				return UserSays{}, fmt.Errorf("apiai: empty annotated text in %q", phrase)
+			}
+			example = true
+		} else {
+			// References must start a word, so emails aren't taken for one.
+			if m[0] > 0 && !strings.ContainsAny(phrase[m[0]-1:m[0]], " \t(\"'") {
+				continue
+			}
+			entity = phrase[m[8]:m[9]]
+			if m[10] >= 0 {
+				alias = phrase[m[10]:m[11]]
+			}
+			template = true
+		}
+		end := m[1]
+		if m[2] < 0 && alias == "" {
+			// A trailing dot ends the sentence, it isn't part of the entity name.
+			trimmed := strings.TrimRight(entity, ".")
+			end -= len(entity) - len(trimmed)
+			entity = trimmed
+		}
+		if entity == "" {
+			return UserSays{}, fmt.Errorf("apiai: invalid entity reference in %q", phrase)
+		}
+		if alias == "" {
+			alias = strings.TrimPrefix(entity, systemEntityPrefix)
+		}
+		if m[2] < 0 {
+			text = "@" + entity + ":" + alias
+		}
+
+		if m[0] > last {
+			us.Data = append(us.Data, Data{Text: phrase[last:m[0]]})
+		}
+		us.Data = append(us.Data, Data{Text: text, Meta: "@" + entity, Alias: alias, UserDefined: example})
+		last = end
+	}
+	if template && example {
+		return UserSays{}, fmt.Errorf("apiai: phrase %q mixes templates and examples", phrase)
+	}
+	if last < len(phrase) {
+		us.Data = append(us.Data, Data{Text: phrase[last:]})
+	}
+	us.IsTemplate = template
+	return us, nil
+}
+
+// Aliases returns the parameter aliases used by the data chunks.
+func (us UserSays) Aliases() []string {
+	var aliases []string
+	for _, d := range us.Data {
+		if d.Alias != "" {
+			aliases = append(aliases, d.Alias)
+		}
+	}
+	return aliases
+}
+
+func (us UserSays) String() string {
+	var b strings.Builder
+	for _, d := range us.Data {
+		switch {
+		case d.Meta == "" || us.IsTemplate:
+			b.WriteString(d.Text)
+		default:
+			fmt.Fprintf(&b, "[%s](%s:%s)", d.Text, d.Meta, d.Alias)
+		}
+	}
+	return b.String()
+}
+
+type ParamOption func(*IntentParameter)
+
+// Required makes the parameter mandatory, asking for it with prompts.
+func Required(prompts ...string) ParamOption {
+	return func(p *IntentParameter) {
+		p.Required = true
+		p.Prompts = prompts
+	}
+}
+
+func IsList() ParamOption {
+	return func(p *IntentParameter) {
+		p.IsList = true
+	}
+}
+
+func DefaultValue(value string) ParamOption {
+	return func(p *IntentParameter) {
+		p.DefaultValue = value
+	}
+}
+
+// IntentBuilder builds intents fluently. Errors are collected and returned by
+// Build, so calls can be chained.
+type IntentBuilder struct {
+	intent   Intent
+	response IntentResponse
+	errs     []string
+}
+
+func NewIntentBuilder(name string) *IntentBuilder {
+	return &IntentBuilder{intent: Intent{Name: name}}
+}
+
+// UserSays adds training phrases, see ParseUserSays for their syntax.
+func (b *IntentBuilder) UserSays(phrases ...string) *IntentBuilder {
+	for _, phrase := range phrases {
+		us, err := ParseUserSays(phrase)
+		if err != nil {
+			b.errs = append(b.errs, strings.TrimPrefix(err.Error(), "apiai: "))
+			continue
+		}
+		b.intent.UserSays = append(b.intent.UserSays, us)
+	}
+	return b
+}
+
+// Param declares a parameter whose value is taken from the alias name.
+func (b *IntentBuilder) Param(name, dataType string, opts ...ParamOption) *IntentBuilder {
+	p := IntentParameter{Name: name, DataType: dataType, Value: "$" + name}
+	for _, opt := range opts {
+		opt(&p)
+	}
+	b.response.Params = append(b.response.Params, p)
+	return b
+}
+
+func (b *IntentBuilder) Action(action string) *IntentBuilder {
+	b.response.Action = action
+	return b
+}
+
+// Speech adds a text response, several texts are picked randomly by api.ai.
+func (b *IntentBuilder) Speech(speech ...string) *IntentBuilder {
+	for _, s := range speech {
+		b.response.Messages = append(b.response.Messages, Message{Type: 0, Speech: s})
+	}
+	return b
+}
+
+// InputContexts sets the contexts that must be active for the intent to match.
+func (b *IntentBuilder) InputContexts(names ...string) *IntentBuilder {
+	b.intent.Contexts = append(b.intent.Contexts, names...)
+	return b
+}
+
+// OutputContext sets a context activated when the intent matches.
+func (b *IntentBuilder) OutputContext(name string, lifespan int) *IntentBuilder {
+	b.response.AffectedContexts = append(b.response.AffectedContexts, Context{Name: name, Lifespan: lifespan})
+	return b
+}
+
+func (b *IntentBuilder) ResetContexts() *IntentBuilder {
+	b.response.ResetContexts = true
+	return b
+}
+
+func (b *IntentBuilder) Event(name string) *IntentBuilder {
+	b.intent.Events = append(b.intent.Events, Event{Name: name})
+	return b
+}
+
+func (b *IntentBuilder) Priority(priority int) *IntentBuilder {
+	b.intent.Priority = priority
+	return b
+}
+
+func (b *IntentBuilder) Fallback() *IntentBuilder {
+	b.intent.FallbackIntent = true
+	return b
+}
+
+func (b *IntentBuilder) Webhook(forSlotFilling bool) *IntentBuilder {
+	b.intent.WebhookUsed = true
+	b.intent.WebhookForSlotFilling = forSlotFilling
+	return b
+}
+
+// Build returns the intent once validated: phrases must parse, and every alias
+// used in them must have a parameter with a matching entity type.
+func (b *IntentBuilder) Build() (Intent, error) {
+	errs := append([]string(nil), b.errs...)
+	if b.intent.Name == "" {
+		errs = append(errs, "intent name is empty")
+	}
+	params := map[string]IntentParameter{}
+	for _, p := range b.response.Params {
+		if _, ok := params[p.Name]; ok {
+			errs = append(errs, fmt.Sprintf("parameter %q is declared twice", p.Name))
+		}
+		params[p.Name] = p
+	}
+	missing, mismatched := map[string]bool{}, map[string]bool{}
+	for _, us := range b.intent.UserSays {
+		for _, d := range us.Data {
+			if d.Alias == "" {
+				continue
+			}
+			p, ok := params[d.Alias]
+			if !ok {
+				missing[d.Alias] = true
+				continue
+			}
+			if p.DataType != d.Meta && !mismatched[d.Alias] {
+				mismatched[d.Alias] = true
+				errs = append(errs, fmt.Sprintf("alias %q is %s but parameter %q is %s", d.Alias, d.Meta, p.Name, p.DataType))
+			}
+		}
+	}
+	var aliases []string
+	for a := range missing {
+		aliases = append(aliases, a)
+	}
+	sort.Strings(aliases)
+	for _, a := range aliases {
+		errs = append(errs, fmt.Sprintf("alias %q has no parameter", a))
+	}
+	if len(errs) > 0 {
+		return Intent{}, fmt.Errorf("apiai: invalid intent %q: %s", b.intent.Name, strings.Join(errs, "; "))
+	}
+
+	intent := b.intent
+	intent.Responses = []IntentResponse{b.response}
+	return intent, nil
+}
@@ -0,0 +1,103 @@
+package apiai
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUserSays(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		description      string
+		phrase           string
+		expectedResponse UserSays
+		expectedError    error
+	}{
+		{
+			description: "template",
+			phrase:      "book a table for @sys.number:guests at @sys.time.",
+			expectedResponse: UserSays{
+				IsTemplate: true,
+				Data: []Data{
+					{Text: "book a table for "},
+					{Text: "@sys.number:guests", Meta: "@sys.number", Alias: "guests"},
+					{Text: " at "},
+					{Text: "@sys.time:time", Meta: "@sys.time", Alias: "time"},
+					{Text: "."},
+				},
+			},
+		}, {
+			description: "example",
+			phrase:      "book a table for [5](@sys.number:guests) at [9pm](@sys.time:time), mail me at me@example.com",
+			expectedResponse: UserSays{
+				Data: []Data{
+					{Text: "book a table for "},
+					{Text: "5", Meta: "@sys.number", Alias: "guests", UserDefined: true},
+					{Text: " at "},
+					{Text: "9pm", Meta: "@sys.time", Alias: "time", UserDefined: true},
+					{Text: ", mail me at me@example.com"},
+				},
+			},
+		}, {
+			description:      "plain text",
+			phrase:           "hello",
+			expectedResponse: UserSays{Data: []Data{{Text: "hello"}}},
+		}, {
+			description:   "mixed styles",
+			phrase:        "book [5](@sys.number:guests) at @sys.time:time",
+			expectedError: fmt.Errorf(`apiai: phrase "book [5](@sys.number:guests) at @sys.time:time" mixes templates and examples`),
+		}, {
+			description:   "empty annotation",
+			phrase:        "book [](@sys.number:guests)",
+			expectedError: fmt.Errorf(`apiai: empty annotated text in "book [](@sys.number:guests)"`),
+		},
+	}
+
+	for _, tc := range tests {
+		r, err := ParseUserSays(tc.phrase)
+
+		assert.Equal(tc.expectedResponse, r, tc.description)
+		assert.Equal(tc.expectedError, err, tc.description)
+	}
+
+	us, _ := ParseUserSays("for [5](@sys.number:guests) at [9pm](@sys.time)")
+	assert.Equal("for [5](@sys.number:guests) at [9pm](@sys.time:time)", us.String())
+}
+
+func TestIntentBuilder(t *testing.T) {
+	assert := assert.New(t)
+
+	intent, err := NewIntentBuilder("book-table").
+		UserSays("book a table for @sys.number:guests at @sys.time:time", "I want a table for [4](@sys.number:guests)").
+		Param("guests", "@sys.number", Required("For how many people?")).
+		Param("time", "@sys.time", DefaultValue("20:00")).
+		Action("table.book").
+		Speech("Table booked for $guests at $time").
+		InputContexts("restaurant").
+		OutputContext("booking", 5).
+		Build()
+
+	assert.Nil(err)
+	assert.Equal("book-table", intent.Name)
+	assert.Equal([]string{"restaurant"}, intent.Contexts)
+	assert.Len(intent.UserSays, 2)
+	assert.Equal([]string{"guests", "time"}, intent.UserSays[0].Aliases())
+	assert.Equal([]IntentResponse{{
+		Action:           "table.book",
+		AffectedContexts: []Context{{Name: "booking", Lifespan: 5}},
+		Params: []IntentParameter{
+			{Name: "guests", Value: "$guests", DataType: "@sys.number", Required: true, Prompts: []string{"For how many people?"}},
+			{Name: "time", Value: "$time", DataType: "@sys.time", DefaultValue: "20:00"},
+		},
+		Messages: []Message{{Type: 0, Speech: "Table booked for $guests at $time"}},
+	}}, intent.Responses)
+
+	_, err = NewIntentBuilder("book-table").
+		UserSays("table for @sys.number:guests at @sys.time:time on @sys.date:date", "for [5](@sys.number:guests").
+		Param("guests", "@sys.any").
+		Build()
+	assert.EqualError(err, `apiai: invalid intent "book-table": alias "guests" is @sys.number but parameter "guests" is @sys.any; alias "date" has no parameter; alias "time" has no parameter`)
+}
@@ -2,7 +2,6 @@ package apiai
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 )
 
@@ -88,7 +87,7 @@ func (c *ApiClient) GetIntents() ([]IntentDescription, error) {
 		}
 		return intents, nil
 	default:
-		return nil, fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 }
 
@@ -110,7 +109,7 @@ func (c *ApiClient) GetIntent(id string) (*Intent, error) {
 		}
 		return intent, nil
 	default:
-		return nil, fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 }
 
@@ -132,7 +131,7 @@ func (c *ApiClient) CreateIntent(intent Intent) (*CreationResponse, error) {
 		}
 		return cr, nil
 	default:
-		return nil, fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 }
 
@@ -148,7 +147,7 @@ func (c *ApiClient) UpdateIntent(id string, intent Intent) error {
 	case http.StatusOK:
 		return nil
 	default:
-		return fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return &StatusError{StatusCode: resp.StatusCode}
 	}
 }
 
@@ -164,6 +163,6 @@ func (c *ApiClient) DeleteIntent(id string) error {
 	case http.StatusOK:
 		return nil
 	default:
-		return fmt.Errorf(DefaultErrorMsg, resp.StatusCode)
+		return &StatusError{StatusCode: resp.StatusCode}
 	}
 }
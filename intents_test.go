@@ -1,7 +1,6 @@
 package apiai
 
 import (
-	"fmt"
 	"net/http"
 	"testing"
 
@@ -155,7 +154,7 @@ func TestGetIntents(t *testing.T) {
 			description:      "api ai failed with an error 400",
 			responder:        httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
 			expectedResponse: nil,
-			expectedError:    fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError:    &StatusError{StatusCode: 400},
 		},
 	}
 
@@ -373,7 +372,7 @@ func TestGetIntent(t *testing.T) {
 			description:      "api ai failed with an error 400",
 			responder:        httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
 			expectedResponse: nil,
-			expectedError:    fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError:    &StatusError{StatusCode: 400},
 		},
 	}
 
@@ -422,7 +421,7 @@ func TestCreateIntent(t *testing.T) {
 			description:      "api ai failed with an error 400",
 			responder:        httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
 			expectedResponse: nil,
-			expectedError:    fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError:    &StatusError{StatusCode: 400},
 		},
 	}
 
@@ -541,7 +540,7 @@ func TestUpdateIntent(t *testing.T) {
 		}, {
 			description:   "api ai failed with an error 400",
 			responder:     httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
-			expectedError: fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError: &StatusError{StatusCode: 400},
 		},
 	}
 
@@ -660,7 +659,7 @@ func TestDeleteIntent(t *testing.T) {
 		}, {
 			description:   "api ai failed with an error 400",
 			responder:     httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
-			expectedError: fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError: &StatusError{StatusCode: 400},
 		},
 	}
 
@@ -0,0 +1,285 @@
+// Package lint checks api.ai intents and entities for common agent design
+// problems.
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/marcossegovia/apiai-go"
+)
+
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+	Info    Severity = "info"
+)
+
+const (
+	RuleDuplicatePhrase   = "duplicate-phrase"
+	RuleFewUserSays       = "few-user-says"
+	RuleMissingPrompts    = "missing-prompts"
+	RuleUnproducedContext = "unproduced-context"
+	RuleUnreachableIntent = "unreachable-intent"
+	RuleUndeclaredAlias   = "undeclared-alias"
+	RuleDuplicateFallback = "duplicate-fallback"
+	RuleUnknownEntity     = "unknown-entity"
+	RuleSynonymCollision  = "synonym-collision"
+)
+
+const (
+	defaultMinUserSays = 3
+	systemEntityPrefix = "@sys."
+	noContexts         = "(none)"
+)
+
+// Finding is a problem found in an intent or entity.
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Intent   string   `json:"intent,omitempty"`
+	Entity   string   `json:"entity,omitempty"`
+	Message  string   `json:"message"`
+}
+
+func (f Finding) String() string {
+	subject := f.Intent
+	if f.Entity != "" {
+		subject = "entity " + f.Entity
+	}
+	return fmt.Sprintf("%s: %s [%s] %s", f.Severity, subject, f.Rule, f.Message)
+}
+
+type Options struct {
+	// MinUserSays is the minimum number of training phrases an intent should
+	// have, defaults to 3.
+	MinUserSays int
+}
+
+// Lint checks intents and entities and returns the findings of intents and
+// then of entities, sorted by name and rule.
+func Lint(intents []apiai.Intent, entities []apiai.Entity, opts Options) []Finding {
+	if opts.MinUserSays <= 0 {
+		opts.MinUserSays = defaultMinUserSays
+	}
+	var findings []Finding
+	findings = append(findings, duplicatePhrases(intents)...)
+	findings = append(findings, contexts(intents)...)
+	findings = append(findings, duplicateFallbacks(intents)...)
+	for _, intent := range intents {
+		findings = append(findings, lintIntent(intent, entities, opts)...)
+	}
+	for _, entity := range entities {
+		for _, c := range apiai.FindSynonymCollisions(entity.Entries) {
+			findings = append(findings, Finding{
+				Rule:     RuleSynonymCollision,
+				Severity: Warning,
+				Entity:   entity.Name,
+				Message:  fmt.Sprintf("synonym %q is used by entries %s", c.Synonym, strings.Join(c.Values, ", ")),
+			})
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if (a.Entity == "") != (b.Entity == "") {
+			return a.Entity == ""
+		}
+		if a.Intent+a.Entity != b.Intent+b.Entity {
+			return a.Intent+a.Entity < b.Intent+b.Entity
+		}
+		return a.Rule < b.Rule
+	})
+	return findings
+}
+
+// WriteJSON writes the findings as a JSON array.
+func WriteJSON(w io.Writer, findings []Finding) error {
+	if findings == nil {
+		findings = []Finding{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(findings)
+}
+
+// HasErrors reports whether any finding has Error severity.
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+func lintIntent(intent apiai.Intent, entities []apiai.Entity, opts Options) []Finding {
+	var findings []Finding
+	add := func(rule string, severity Severity, format string, args ...interface{}) {
+		findings = append(findings, Finding{Rule: rule, Severity: severity, Intent: intent.Name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	reachable := len(intent.UserSays) > 0 || len(intent.Events) > 0 || intent.FallbackIntent
+	if !reachable {
+		add(RuleUnreachableIntent, Error, "intent has no training phrases nor events")
+	}
+	if len(intent.Events) == 0 && !intent.FallbackIntent && len(intent.UserSays) > 0 && len(intent.UserSays) < opts.MinUserSays {
+		add(RuleFewUserSays, Warning, "intent has %d training phrases, at least %d are recommended", len(intent.UserSays), opts.MinUserSays)
+	}
+
+	params := map[string]bool{}
+	for _, r := range intent.Responses {
+		for _, p := range r.Params {
+			params[p.Name] = true
+			if p.Required && len(p.Prompts) == 0 {
+				add(RuleMissingPrompts, Error, "required parameter %q has no prompts", p.Name)
+			}
+		}
+	}
+
+	known := map[string]bool{}
+	for _, e := range entities {
+		known["@"+e.Name] = true
+	}
+	reported := map[string]bool{}
+	for _, us := range intent.UserSays {
+		for _, d := range us.Data {
+			if d.Alias != "" && !params[d.Alias] && !reported["alias "+d.Alias] {
+				reported["alias "+d.Alias] = true
+				add(RuleUndeclaredAlias, Error, "alias %q has no parameter", d.Alias)
+			}
+			if entities != nil && d.Meta != "" && !strings.HasPrefix(d.Meta, systemEntityPrefix) && !known[d.Meta] && !reported["meta "+d.Meta] {
+				reported["meta "+d.Meta] = true
+				add(RuleUnknownEntity, Error, "training phrases reference unknown entity %s", d.Meta)
+			}
+		}
+	}
+	return findings
+}
+
+func normalizePhrase(us apiai.UserSays) string {
+	var b strings.Builder
+	for _, d := range us.Data {
+		if d.Meta != "" && !us.IsTemplate {
+			// Annotated examples are compared by their entity, not their value.
+			b.WriteString(d.Meta)
+			continue
+		}
+		b.WriteString(d.Text)
+	}
+	return apiai.NormalizeSynonym(b.String())
+}
+
+func duplicatePhrases(intents []apiai.Intent) []Finding {
+	owners := map[string][]string{}
+	var phrases []string
+	for _, intent := range intents {
+		seen := map[string]bool{}
+		for _, us := range intent.UserSays {
+			p := normalizePhrase(us)
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			if _, ok := owners[p]; !ok {
+				phrases = append(phrases, p)
+			}
+			owners[p] = append(owners[p], intent.Name)
+		}
+	}
+	var findings []Finding
+	for _, p := range phrases {
+		if len(owners[p]) < 2 {
+			continue
+		}
+		for _, name := range owners[p] {
+			findings = append(findings, Finding{
+				Rule:     RuleDuplicatePhrase,
+				Severity: Error,
+				Intent:   name,
+				Message:  fmt.Sprintf("training phrase %q is also in intents %s", p, strings.Join(others(owners[p], name), ", ")),
+			})
+		}
+	}
+	return findings
+}
+
+func contexts(intents []apiai.Intent) []Finding {
+	produced := map[string]bool{}
+	for _, intent := range intents {
+		for _, r := range intent.Responses {
+			for _, c := range r.AffectedContexts {
+				if c.Lifespan > 0 {
+					produced[strings.ToLower(c.Name)] = true
+				}
+			}
+		}
+	}
+	var findings []Finding
+	for _, intent := range intents {
+		for _, c := range intent.Contexts {
+			if !produced[strings.ToLower(c)] {
+				findings = append(findings, Finding{
+					Rule:     RuleUnproducedContext,
+					Severity: Warning,
+					Intent:   intent.Name,
+					Message:  fmt.Sprintf("input context %q is not produced by any intent, it can only be set by the client", c),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+func duplicateFallbacks(intents []apiai.Intent) []Finding {
+	byContexts := map[string][]string{}
+	var keys []string
+	for _, intent := range intents {
+		if !intent.FallbackIntent {
+			continue
+		}
+		contexts := make([]string, len(intent.Contexts))
+		for i, c := range intent.Contexts {
+			contexts[i] = strings.ToLower(c)
+		}
+		sort.Strings(contexts)
+		key := strings.Join(contexts, ", ")
+		if key == "" {
+			key = noContexts
+		}
+		if _, ok := byContexts[key]; !ok {
+			keys = append(keys, key)
+		}
+		byContexts[key] = append(byContexts[key], intent.Name)
+	}
+	var findings []Finding
+	for _, key := range keys {
+		names := byContexts[key]
+		if len(names) < 2 {
+			continue
+		}
+		for _, name := range names {
+			findings = append(findings, Finding{
+				Rule:     RuleDuplicateFallback,
+				Severity: Error,
+				Intent:   name,
+				Message:  fmt.Sprintf("fallback intents %s share the contexts %s", strings.Join(others(names, name), ", "), key),
+			})
+		}
+	}
+	return findings
+}
+
+func others(names []string, name string) []string {
+	var rest []string
+	for _, n := range names {
+		if n != name {
+			rest = append(rest, n)
+		}
+	}
+	return rest
+}
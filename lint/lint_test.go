@@ -0,0 +1,84 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/marcossegovia/apiai-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func phrases(texts ...string) []apiai.UserSays {
+	userSays := make([]apiai.UserSays, len(texts))
+	for i, text := range texts {
+		us, err := apiai.ParseUserSays(text)
+		if err != nil {
+			panic(err)
+		}
+		userSays[i] = us
+	}
+	return userSays
+}
+
+func TestLint(t *testing.T) {
+	entities := []apiai.Entity{{Name: "drink", Entries: []apiai.Entry{
+		{Value: "coffee", Synonyms: []string{"coffee", "java"}},
+		{Value: "tea", Synonyms: []string{"tea", "Java"}},
+	}}}
+	intents := []apiai.Intent{
+		{
+			Name:     "order",
+			UserSays: phrases("I want a [coffee](@drink:drink)", "a [tea](@drink:drink) please", "give me a [coffee](@size:size)"),
+			Responses: []apiai.IntentResponse{{
+				Params:           []apiai.IntentParameter{{Name: "drink", DataType: "@drink", Value: "$drink", Required: true}},
+				AffectedContexts: []apiai.Context{{Name: "ordering", Lifespan: 2}},
+			}},
+		},
+		{
+			Name:     "order.again",
+			UserSays: phrases("I want a [tea](@drink:drink)"),
+			Responses: []apiai.IntentResponse{{
+				Params: []apiai.IntentParameter{{Name: "drink", DataType: "@drink", Value: "$drink", Required: true, Prompts: []string{"What?"}}},
+			}},
+		},
+		{Name: "confirm", Contexts: []string{"Ordering", "payment"}, UserSays: phrases("yes", "sure", "ok")},
+		{Name: "welcome", Events: []apiai.Event{{Name: "WELCOME"}}},
+		{Name: "orphan"},
+		{Name: "fallback", FallbackIntent: true},
+		{Name: "fallback.again", FallbackIntent: true},
+		{Name: "fallback.ordering", FallbackIntent: true, Contexts: []string{"ordering"}},
+	}
+
+	findings := Lint(intents, entities, Options{})
+	assert.Equal(t, []Finding{
+		{RuleUnproducedContext, Warning, "confirm", "", `input context "payment" is not produced by any intent, it can only be set by the client`},
+		{RuleDuplicateFallback, Error, "fallback", "", "fallback intents fallback.again share the contexts (none)"},
+		{RuleDuplicateFallback, Error, "fallback.again", "", "fallback intents fallback share the contexts (none)"},
+		{RuleDuplicatePhrase, Error, "order", "", `training phrase "i want a @drink" is also in intents order.again`},
+		{RuleMissingPrompts, Error, "order", "", `required parameter "drink" has no prompts`},
+		{RuleUndeclaredAlias, Error, "order", "", `alias "size" has no parameter`},
+		{RuleUnknownEntity, Error, "order", "", "training phrases reference unknown entity @size"},
+		{RuleDuplicatePhrase, Error, "order.again", "", `training phrase "i want a @drink" is also in intents order`},
+		{RuleFewUserSays, Warning, "order.again", "", "intent has 1 training phrases, at least 3 are recommended"},
+		{RuleUnreachableIntent, Error, "orphan", "", "intent has no training phrases nor events"},
+		{RuleSynonymCollision, Warning, "", "drink", `synonym "java" is used by entries coffee, tea`},
+	}, findings)
+	assert.True(t, HasErrors(findings))
+	assert.Equal(t, `error: order [missing-prompts] required parameter "drink" has no prompts`, findings[4].String())
+	assert.Equal(t, `warning: entity drink [synonym-collision] synonym "java" is used by entries coffee, tea`, findings[10].String())
+
+	assert.Empty(t, Lint(intents[1:2], nil, Options{MinUserSays: 1}))
+}
+
+func TestWriteJSON(t *testing.T) {
+	buf := new(bytes.Buffer)
+	assert.Nil(t, WriteJSON(buf, nil))
+	assert.Equal(t, "[]\n", buf.String())
+
+	buf.Reset()
+	assert.Nil(t, WriteJSON(buf, []Finding{{Rule: RuleUnreachableIntent, Severity: Error, Intent: "orphan", Message: "unreachable"}}))
+	var decoded []map[string]string
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, []map[string]string{{"rule": "unreachable-intent", "severity": "error", "intent": "orphan", "message": "unreachable"}}, decoded)
+}
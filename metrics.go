@@ -0,0 +1,257 @@
+package apiai
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives measures of the requests of an ApiClient, for instance to
+// export them to Prometheus. Set it in ClientConfig.Metrics. Endpoints are
+// request paths with ids replaced by :id, like "entities/:id/entries", and
+// status is 0 when the request got no response. Implementations must be safe
+// for concurrent use.
+type Metrics interface {
+	ObserveRequest(endpoint, method string, status int, duration time.Duration)
+	ObserveRetry(endpoint string)
+	// ObserveQuery is called for every answered query. Intent is empty when
+	// no intent matched.
+	ObserveQuery(intent string, fallback bool, score float64)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveRequest(string, string, int, time.Duration) {}
+func (nopMetrics) ObserveRetry(string)                               {}
+func (nopMetrics) ObserveQuery(string, bool, float64)                {}
+
+func (c *ApiClient) metrics() Metrics {
+	if c.config.Metrics == nil {
+		return nopMetrics{}
+	}
+	return c.config.Metrics
+}
+
+// doRequest sends req measuring it as a request to endpoint.
+func (c *ApiClient) doRequest(httpClient *http.Client, req *http.Request, endpoint string) (*http.Response, error) {
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	status := 0
+	if err == nil {
+		status = resp.StatusCode
+	}
+	c.metrics().ObserveRequest(endpointLabel(endpoint), req.Method, status, time.Since(start))
+	return resp, err
+}
+
+// endpointLabel replaces the ids and names in a request path, so that
+// endpoints don't create a label value per intent or entity.
+func endpointLabel(path string) string {
+	segments := strings.Split(path, "/")
+	for i := 1; i < len(segments); i += 2 {
+		segments[i] = ":id"
+	}
+	return strings.Join(segments, "/")
+}
+
+// isFallback reports whether the answer comes from no intent or from a
+// fallback intent, which use the input.unknown action by default.
+func isFallback(r Result) bool {
+	return r.Metadata.IntentName == "" || r.Action == UnknownInputAction
+}
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the latency
+// histograms of MemoryMetrics.
+var DefaultLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// DefaultScoreBuckets are the upper bounds of the score histogram of
+// MemoryMetrics.
+var DefaultScoreBuckets = []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1}
+
+type histogram struct {
+	bounds []float64
+	counts []int
+	sum    float64
+	count  int
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]int, len(bounds))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range h.bounds {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+type requestKey struct {
+	endpoint, method string
+	status           int
+}
+
+// MemoryMetrics keeps the metrics in memory and writes them in the Prometheus
+// text format. It's an http.Handler, to serve them on /metrics.
+type MemoryMetrics struct {
+	mu        sync.Mutex
+	requests  map[requestKey]int
+	latencies map[string]*histogram
+	retries   map[string]int
+	intents   map[string]int
+	fallbacks int
+	queries   int
+	scores    *histogram
+}
+
+func NewMemoryMetrics() *MemoryMetrics {
+	return &MemoryMetrics{
+		requests:  map[requestKey]int{},
+		latencies: map[string]*histogram{},
+		retries:   map[string]int{},
+		intents:   map[string]int{},
+		scores:    newHistogram(DefaultScoreBuckets),
+	}
+}
+
+func (m *MemoryMetrics) ObserveRequest(endpoint, method string, status int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[requestKey{endpoint, method, status}]++
+	h, ok := m.latencies[endpoint]
+	if !ok {
+		h = newHistogram(DefaultLatencyBuckets)
+		m.latencies[endpoint] = h
+	}
+	h.observe(duration.Seconds())
+}
+
+func (m *MemoryMetrics) ObserveRetry(endpoint string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries[endpoint]++
+}
+
+func (m *MemoryMetrics) ObserveQuery(intent string, fallback bool, score float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queries++
+	if fallback {
+		m.fallbacks++
+	} else {
+		m.intents[intent]++
+	}
+	m.scores.observe(score)
+}
+
+// HitRate is the share of queries answered by an intent other than a
+// fallback one.
+func (m *MemoryMetrics) HitRate() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.queries == 0 {
+		return 0
+	}
+	return float64(m.queries-m.fallbacks) / float64(m.queries)
+}
+
+func (m *MemoryMetrics) FallbackRate() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.queries == 0 {
+		return 0
+	}
+	return float64(m.fallbacks) / float64(m.queries)
+}
+
+func (m *MemoryMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.WritePrometheus(w)
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition
+// format, sorted by labels.
+func (m *MemoryMetrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var b strings.Builder
+
+	b.WriteString("# HELP apiai_requests_total Requests to api.ai by endpoint, method and status code.\n")
+	b.WriteString("# TYPE apiai_requests_total counter\n")
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, c := keys[i], keys[j]
+		if a.endpoint != c.endpoint {
+			return a.endpoint < c.endpoint
+		}
+		if a.method != c.method {
+			return a.method < c.method
+		}
+		return a.status < c.status
+	})
+	for _, k := range keys {
+		fmt.Fprintf(&b, "apiai_requests_total{endpoint=%q,method=%q,status=\"%d\"} %d\n", k.endpoint, k.method, k.status, m.requests[k])
+	}
+
+	b.WriteString("# HELP apiai_request_duration_seconds Latency of the requests to api.ai by endpoint.\n")
+	b.WriteString("# TYPE apiai_request_duration_seconds histogram\n")
+	for _, endpoint := range sortedMapKeys(m.latencies) {
+		writeHistogram(&b, "apiai_request_duration_seconds", fmt.Sprintf("endpoint=%q,", endpoint), m.latencies[endpoint])
+	}
+
+	b.WriteString("# HELP apiai_retries_total Retried requests to api.ai by endpoint.\n")
+	b.WriteString("# TYPE apiai_retries_total counter\n")
+	for _, endpoint := range sortedMapKeys(m.retries) {
+		fmt.Fprintf(&b, "apiai_retries_total{endpoint=%q} %d\n", endpoint, m.retries[endpoint])
+	}
+
+	b.WriteString("# HELP apiai_queries_total Answered queries.\n")
+	b.WriteString("# TYPE apiai_queries_total counter\n")
+	fmt.Fprintf(&b, "apiai_queries_total %d\n", m.queries)
+	b.WriteString("# HELP apiai_fallback_queries_total Queries answered by no intent or a fallback intent.\n")
+	b.WriteString("# TYPE apiai_fallback_queries_total counter\n")
+	fmt.Fprintf(&b, "apiai_fallback_queries_total %d\n", m.fallbacks)
+	b.WriteString("# HELP apiai_intent_hits_total Queries answered by each intent.\n")
+	b.WriteString("# TYPE apiai_intent_hits_total counter\n")
+	for _, intent := range sortedMapKeys(m.intents) {
+		fmt.Fprintf(&b, "apiai_intent_hits_total{intent=%q} %d\n", intent, m.intents[intent])
+	}
+	b.WriteString("# HELP apiai_query_score Score of the answered queries.\n")
+	b.WriteString("# TYPE apiai_query_score histogram\n")
+	writeHistogram(&b, "apiai_query_score", "", m.scores)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeHistogram(b *strings.Builder, name, labels string, h *histogram) {
+	for i, bound := range h.bounds {
+		fmt.Fprintf(b, "%s_bucket{%sle=\"%g\"} %d\n", name, labels, bound, h.counts[i])
+	}
+	fmt.Fprintf(b, "%s_bucket{%sle=\"+Inf\"} %d\n", name, labels, h.count)
+	labels = strings.TrimSuffix(labels, ",")
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(b, "%s_sum%s %g\n", name, labels, h.sum)
+	fmt.Fprintf(b, "%s_count%s %d\n", name, labels, h.count)
+}
+
+func sortedMapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
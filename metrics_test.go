@@ -0,0 +1,110 @@
+package apiai
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointLabel(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"query", "query"},
+		{"intents", "intents"},
+		{"intents/1234-abcd", "intents/:id"},
+		{"entities/drink/entries", "entities/:id/entries"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, endpointLabel(test.path))
+	}
+}
+
+func TestClientMetrics(t *testing.T) {
+	metrics := NewMemoryMetrics()
+	c, err := NewClient(&ClientConfig{Token: "fakeToken", Metrics: metrics})
+	if err != nil {
+		t.FailNow()
+	}
+	assert := assert.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	answers := []string{
+		`{"result": {"action": "order.create", "score": 0.85, "metadata": {"intentName": "order"}}}`,
+		`{"result": {"action": "input.unknown", "score": 0.4, "metadata": {"intentName": "Default Fallback Intent"}}}`,
+		`{"result": {"action": "order.create", "score": 0.95, "metadata": {"intentName": "order"}}}`,
+	}
+	httpmock.RegisterResponder("POST", c.buildUrl("query", nil), func(req *http.Request) (*http.Response, error) {
+		answer := answers[0]
+		answers = answers[1:]
+		return httpmock.NewStringResponse(200, answer), nil
+	})
+	httpmock.RegisterResponder("GET", c.buildUrl("intents/i1", nil), httpmock.NewStringResponder(404, `{}`))
+	failures := 1
+	httpmock.RegisterResponder("POST", c.buildUrl("entities/drink/entries", nil), func(req *http.Request) (*http.Response, error) {
+		if failures > 0 {
+			failures--
+			return httpmock.NewStringResponse(500, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200, `{}`), nil
+	})
+
+	for i := 0; i < 3; i++ {
+		_, err = c.Query(Query{Query: []string{"a coffee"}})
+		assert.Nil(err)
+	}
+	_, err = c.GetIntent("i1")
+	assert.Error(err)
+	assert.Nil(c.AddEntriesChunked("drink", []Entry{{Value: "tea"}}, BulkOptions{Retries: 1}))
+
+	assert.Equal(2.0/3, metrics.HitRate())
+	assert.Equal(1.0/3, metrics.FallbackRate())
+
+	server := httptest.NewServer(metrics)
+	defer server.Close()
+	httpmock.DeactivateAndReset()
+	resp, err := http.Get(server.URL + "/metrics")
+	assert.Nil(err)
+	defer resp.Body.Close()
+	buf := new(bytes.Buffer)
+	buf.ReadFrom(resp.Body)
+	out := buf.String()
+
+	for _, line := range []string{
+		`apiai_requests_total{endpoint="entities/:id/entries",method="POST",status="200"} 1`,
+		`apiai_requests_total{endpoint="entities/:id/entries",method="POST",status="500"} 1`,
+		`apiai_requests_total{endpoint="intents/:id",method="GET",status="404"} 1`,
+		`apiai_requests_total{endpoint="query",method="POST",status="200"} 3`,
+		`apiai_request_duration_seconds_count{endpoint="query"} 3`,
+		`apiai_request_duration_seconds_bucket{endpoint="query",le="+Inf"} 3`,
+		`apiai_retries_total{endpoint="entities/:id/entries"} 1`,
+		`apiai_queries_total 3`,
+		`apiai_fallback_queries_total 1`,
+		`apiai_intent_hits_total{intent="order"} 2`,
+		`apiai_query_score_bucket{le="0.5"} 1`,
+		`apiai_query_score_bucket{le="0.9"} 2`,
+		`apiai_query_score_bucket{le="+Inf"} 3`,
+		`apiai_query_score_sum 2.2`,
+		`# TYPE apiai_request_duration_seconds histogram`,
+	} {
+		assert.Contains(out, line+"\n")
+	}
+}
+
+func TestMemoryMetricsRequestErrors(t *testing.T) {
+	m := NewMemoryMetrics()
+	m.ObserveRequest("tts", "GET", 0, 3*time.Second)
+	buf := new(bytes.Buffer)
+	assert.Nil(t, m.WritePrometheus(buf))
+	assert.Contains(t, buf.String(), `apiai_requests_total{endpoint="tts",method="GET",status="0"} 1`+"\n")
+	assert.Contains(t, buf.String(), `apiai_request_duration_seconds_bucket{endpoint="tts",le="2.5"} 0`+"\n")
+	assert.Contains(t, buf.String(), `apiai_request_duration_seconds_bucket{endpoint="tts",le="5"} 1`+"\n")
+	assert.Equal(t, 0.0, m.HitRate())
+}
@@ -0,0 +1,190 @@
+package apiai
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// AgentRule routes the queries that meet all its conditions to Agent. Prefix
+// is matched regardless of case and removed from the query, and Context must
+// be active in the session of Agent.
+type AgentRule struct {
+	Agent    string
+	Language string
+	Prefix   string
+	Context  string
+}
+
+func (r AgentRule) matches(q Query, contexts []Context) bool {
+	if r.Language != "" && !strings.EqualFold(r.Language, q.Language) {
+		return false
+	}
+	if r.Prefix != "" && (len(q.Query) == 0 || !hasPrefixFold(q.Query[0], r.Prefix)) {
+		return false
+	}
+	if r.Context != "" {
+		if _, ok := FindContext(contexts, r.Context); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// MultiAgentClient queries several agents, for instance one per domain, as a
+// single one. Queries go to the agent of the first matching rule, or to
+// Default. Without rule nor default, every agent is queried in parallel and the
+// answer with the highest score wins. Note that all of them see the query, and
+// may change their contexts.
+//
+// Every agent keeps its own contexts under the same sessionId. Answers carry
+// the contexts of every agent, the ones of the answering agent first.
+type MultiAgentClient struct {
+	Rules   []AgentRule
+	Default string
+
+	agents   map[string]Querier
+	names    []string
+	mu       sync.Mutex
+	contexts map[string]map[string][]Context
+	sessions []string
+}
+
+func NewMultiAgentClient() *MultiAgentClient {
+	return &MultiAgentClient{agents: map[string]Querier{}, contexts: map[string]map[string][]Context{}}
+}
+
+// AddAgent adds an agent, such as an ApiClient with the agent token, before
+// querying. Agents added first win ties when querying in parallel.
+func (m *MultiAgentClient) AddAgent(name string, q Querier) {
+	if _, ok := m.agents[name]; !ok {
+		m.names = append(m.names, name)
+	}
+	m.agents[name] = q
+}
+
+func (m *MultiAgentClient) Query(q Query) (*QueryResponse, error) {
+	resp, _, err := m.QueryAgent(q)
+	return resp, err
+}
+
+// QueryAgent is like Query but also returns the name of the agent that
+// answered.
+func (m *MultiAgentClient) QueryAgent(q Query) (*QueryResponse, string, error) {
+	if len(m.agents) == 0 {
+		return nil, "", fmt.Errorf("apiai: no agents to query")
+	}
+	for _, r := range m.Rules {
+		if !r.matches(q, m.Contexts(q.SessionId)[r.Agent]) {
+			continue
+		}
+		if r.Prefix != "" {
+			q.Query = append([]string{}, q.Query...)
+			q.Query[0] = strings.TrimSpace(q.Query[0][len(r.Prefix):])
+		}
+		return m.queryOne(r.Agent, q)
+	}
+	if m.Default != "" {
+		return m.queryOne(m.Default, q)
+	}
+	return m.queryAll(q)
+}
+
+func (m *MultiAgentClient) queryOne(name string, q Query) (*QueryResponse, string, error) {
+	agent, ok := m.agents[name]
+	if !ok {
+		return nil, "", fmt.Errorf("apiai: unknown agent %q", name)
+	}
+	resp, err := agent.Query(q)
+	if err != nil {
+		return nil, name, fmt.Errorf("apiai: agent %q, %w", name, err)
+	}
+	m.merge(q.SessionId, name, resp)
+	return resp, name, nil
+}
+
+func (m *MultiAgentClient) queryAll(q Query) (*QueryResponse, string, error) {
+	responses := make([]*QueryResponse, len(m.names))
+	errs := make([]error, len(m.names))
+	var wg sync.WaitGroup
+	for i, name := range m.names {
+		wg.Add(1)
+		go func(i int, agent Querier) {
+			defer wg.Done()
+			responses[i], errs[i] = agent.Query(q)
+		}(i, m.agents[name])
+	}
+	wg.Wait()
+
+	best := -1
+	for i, resp := range responses {
+		if errs[i] != nil {
+			continue
+		}
+		m.track(q.SessionId, m.names[i], resp.Result.Contexts)
+		if best < 0 || betterAnswer(resp, responses[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil, "", fmt.Errorf("apiai: agent %q, %w", m.names[0], errs[0])
+	}
+	m.merge(q.SessionId, m.names[best], responses[best])
+	return responses[best], m.names[best], nil
+}
+
+// betterAnswer prefers answers that matched an intent, then higher scores.
+func betterAnswer(a, b *QueryResponse) bool {
+	matchedA, matchedB := a.Result.Metadata.IntentName != "", b.Result.Metadata.IntentName != ""
+	if matchedA != matchedB {
+		return matchedA
+	}
+	return a.Result.Score > b.Result.Score
+}
+
+func (m *MultiAgentClient) track(sessionId, agent string, contexts []Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.contexts[sessionId]
+	if !ok {
+		// Forget the oldest session once maxTrackedSessions are tracked.
+		if len(m.sessions) >= maxTrackedSessions {
+			delete(m.contexts, m.sessions[0])
+			m.sessions = m.sessions[1:]
+		}
+		m.sessions = append(m.sessions, sessionId)
+		session = map[string][]Context{}
+		m.contexts[sessionId] = session
+	}
+	session[agent] = contexts
+}
+
+// merge records the contexts of the answering agent and sets the contexts of
+// the answer to the ones of every agent.
+func (m *MultiAgentClient) merge(sessionId, agent string, resp *QueryResponse) {
+	m.track(sessionId, agent, resp.Result.Contexts)
+	contexts := m.Contexts(sessionId)
+	merged := append([]Context{}, contexts[agent]...)
+	for _, name := range m.names {
+		if name != agent {
+			merged = append(merged, contexts[name]...)
+		}
+	}
+	resp.Result.Contexts = merged
+}
+
+// Contexts returns the contexts active in every agent after the last answer
+// of the session.
+func (m *MultiAgentClient) Contexts(sessionId string) map[string][]Context {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	contexts := map[string][]Context{}
+	for agent, c := range m.contexts[sessionId] {
+		contexts[agent] = c
+	}
+	return contexts
+}
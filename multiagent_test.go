@@ -0,0 +1,110 @@
+package apiai
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiAgentClient(t *testing.T) {
+	assert := assert.New(t)
+
+	var mu sync.Mutex
+	queried := map[string][]Query{}
+	agent := func(name string, score float64, contexts ...Context) Querier {
+		return queryFunc(func(q Query) (*QueryResponse, error) {
+			mu.Lock()
+			queried[name] = append(queried[name], q)
+			mu.Unlock()
+			if q.Query[0] == "boom" {
+				return nil, errors.New("timeout")
+			}
+			resp := &QueryResponse{SessionId: q.SessionId}
+			resp.Result.Score = score
+			resp.Result.Contexts = contexts
+			if score > 0 {
+				resp.Result.Metadata.IntentName = name + ".intent"
+			}
+			return resp, nil
+		})
+	}
+
+	m := NewMultiAgentClient()
+	_, err := m.Query(Query{Query: []string{"hi"}})
+	assert.EqualError(err, "apiai: no agents to query")
+
+	m.AddAgent("shop", agent("shop", 0.7, Context{Name: "ordering", Lifespan: 2}))
+	m.AddAgent("billing", agent("billing", 0.9, Context{Name: "invoice", Lifespan: 1}))
+	m.AddAgent("smalltalk", agent("smalltalk", 0))
+
+	resp, name, err := m.QueryAgent(Query{Query: []string{"a coffee"}, SessionId: "s1"})
+	assert.Nil(err)
+	assert.Equal("billing", name)
+	assert.Equal("billing.intent", resp.Result.Metadata.IntentName)
+	assert.Equal([]Context{{Name: "invoice", Lifespan: 1}, {Name: "ordering", Lifespan: 2}}, resp.Result.Contexts)
+	assert.Len(queried["smalltalk"], 1)
+
+	m.Rules = []AgentRule{
+		{Agent: "smalltalk", Language: "es"},
+		{Agent: "billing", Prefix: "billing:"},
+		{Agent: "shop", Context: "ORDERING"},
+	}
+	_, name, err = m.QueryAgent(Query{Query: []string{"hola"}, Language: "es", SessionId: "s1"})
+	assert.Nil(err)
+	assert.Equal("smalltalk", name)
+
+	_, name, err = m.QueryAgent(Query{Query: []string{"Billing: my invoice"}, SessionId: "s1"})
+	assert.Nil(err)
+	assert.Equal("billing", name)
+	assert.Equal("my invoice", queried["billing"][len(queried["billing"])-1].Query[0])
+
+	resp, name, err = m.QueryAgent(Query{Query: []string{"large"}, SessionId: "s1"})
+	assert.Nil(err)
+	assert.Equal("shop", name, "the context is active in the shop agent")
+	assert.Equal("s1", resp.SessionId)
+	assert.Equal("s1", queried["shop"][len(queried["shop"])-1].SessionId)
+
+	_, name, err = m.QueryAgent(Query{Query: []string{"large"}, SessionId: "s2"})
+	assert.Nil(err)
+	assert.Equal("billing", name, "contexts are tracked per session")
+
+	m.Rules = nil
+	m.Default = "shop"
+	_, err = m.Query(Query{Query: []string{"boom"}})
+	assert.EqualError(err, `apiai: agent "shop", timeout`)
+	m.Default = "unknown"
+	_, err = m.Query(Query{Query: []string{"hi"}})
+	assert.EqualError(err, `apiai: unknown agent "unknown"`)
+
+	m.Default = ""
+	_, err = m.Query(Query{Query: []string{"boom"}})
+	assert.EqualError(err, `apiai: agent "shop", timeout`)
+
+	assert.Equal(map[string][]Context{
+		"shop":      {{Name: "ordering", Lifespan: 2}},
+		"billing":   {{Name: "invoice", Lifespan: 1}},
+		"smalltalk": nil,
+	}, m.Contexts("s1"))
+}
+
+func TestMultiAgentClientEviction(t *testing.T) {
+	assert := assert.New(t)
+	m := NewMultiAgentClient()
+
+	m.track("s1", "shop", []Context{{Name: "ordering", Lifespan: 2}})
+	for i := 1; i < maxTrackedSessions; i++ {
+		m.track(fmt.Sprint(i), "shop", nil)
+	}
+	assert.Len(m.contexts, maxTrackedSessions)
+	assert.Equal([]Context{{Name: "ordering", Lifespan: 2}}, m.Contexts("s1")["shop"])
+
+	m.track("s1", "billing", nil)
+	m.track("new", "shop", nil)
+	assert.Len(m.contexts, maxTrackedSessions)
+	assert.Empty(m.Contexts("s1"), "the oldest session is forgotten")
+	assert.NotEmpty(m.Contexts("1"), "other sessions are kept")
+	assert.NotEmpty(m.Contexts("new"))
+}
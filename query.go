@@ -25,17 +25,17 @@ type Event struct {
 }
 
 type Query struct {
-	Query           []string            `json:"query"`
-	Event           Event               `json:"event"`
-	Version         string              `json:"-"`
-	SessionId       string              `json:"sessionId"`
-	Language        string              `json:"lang"`
-	Contexts        []Context           `json:"contexts"`
-	ResetContexts   bool                `json:"resetContexts"`
-	Entities        []EntityDescription `json:"entities"`
-	Timezone        string              `json:"timezone"`
-	Location        Location            `json:"location"`
-	OriginalRequest Platform            `json:"originalRequest"`
+	Query           []string     `json:"query"`
+	Event           Event        `json:"event"`
+	Version         string       `json:"-"`
+	SessionId       string       `json:"sessionId"`
+	Language        string       `json:"lang"`
+	Contexts        []Context    `json:"contexts"`
+	ResetContexts   bool         `json:"resetContexts"`
+	Entities        []UserEntity `json:"entities"`
+	Timezone        string       `json:"timezone"`
+	Location        Location     `json:"location"`
+	OriginalRequest Platform     `json:"originalRequest"`
 }
 
 type CreationResponse struct {
@@ -124,7 +124,7 @@ func (c *ApiClient) Query(q Query) (*QueryResponse, error) {
 		}
 	}
 
-	resp, err := httpClient.Do(req)
+	resp, err := c.doRequest(httpClient, req, "query")
 	if err != nil {
 		return nil, err
 	}
@@ -138,8 +138,9 @@ func (c *ApiClient) Query(q Query) (*QueryResponse, error) {
 		if err != nil {
 			return nil, err
 		}
+		c.metrics().ObserveQuery(response.Result.Metadata.IntentName, isFallback(response.Result), response.Result.Score)
 		return response, nil
 	default:
-		return nil, fmt.Errorf("apiai: wops something happens because status code is %v", resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 }
@@ -1,7 +1,6 @@
 package apiai
 
 import (
-	"fmt"
 	"net/http"
 	"testing"
 	"time"
@@ -93,7 +92,7 @@ func TestQuery(t *testing.T) {
 			description:      "api ai failed with an error 400",
 			responder:        httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
 			expectedResponse: nil,
-			expectedError:    fmt.Errorf("apiai: wops something happens because status code is 400"),
+			expectedError:    &StatusError{StatusCode: 400},
 		},
 	}
 
@@ -0,0 +1,343 @@
+package apiai
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const noIntent = "(none)"
+
+// RegressionCase is an utterance and what the agent is expected to
+// recognize. Empty expectations are not checked. Before are utterances sent
+// first in the same session, to set up contexts.
+type RegressionCase struct {
+	Name       string                 `yaml:"name" json:"name"`
+	Before     []string               `yaml:"before" json:"before,omitempty"`
+	Query      string                 `yaml:"query" json:"query"`
+	Intent     string                 `yaml:"intent" json:"intent,omitempty"`
+	Action     string                 `yaml:"action" json:"action,omitempty"`
+	Parameters map[string]interface{} `yaml:"parameters" json:"parameters,omitempty"`
+	Contexts   []string               `yaml:"contexts" json:"contexts,omitempty"`
+}
+
+type RegressionSuite struct {
+	Name  string           `yaml:"name"`
+	Cases []RegressionCase `yaml:"cases"`
+}
+
+// LoadRegressionSuite reads a suite in YAML or JSON.
+func LoadRegressionSuite(r io.Reader) (*RegressionSuite, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var suite RegressionSuite
+	if err := yaml.UnmarshalStrict(b, &suite); err != nil {
+		return nil, fmt.Errorf("apiai: invalid regression suite, %v", err)
+	}
+	for i, c := range suite.Cases {
+		if c.Query == "" {
+			return nil, fmt.Errorf("apiai: invalid regression suite, case %d has no query", i+1)
+		}
+		if c.Name == "" {
+			suite.Cases[i].Name = c.Query
+		}
+		for k, v := range c.Parameters {
+			c.Parameters[k] = jsonCompatible(v)
+		}
+	}
+	return &suite, nil
+}
+
+func LoadRegressionSuiteFile(path string) (*RegressionSuite, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadRegressionSuite(f)
+}
+
+// jsonCompatible converts the maps decoded from YAML to maps with string keys.
+func jsonCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = jsonCompatible(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range v {
+			v[i] = jsonCompatible(e)
+		}
+	}
+	return v
+}
+
+type CaseResult struct {
+	Case     RegressionCase `json:"case"`
+	Intent   string         `json:"intent"`
+	Action   string         `json:"action"`
+	Score    float64        `json:"score"`
+	Diffs    []FieldChange  `json:"diffs,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Duration time.Duration  `json:"duration"`
+}
+
+func (r CaseResult) Passed() bool {
+	return r.Error == "" && len(r.Diffs) == 0
+}
+
+type RegressionReport struct {
+	Suite    string       `json:"suite"`
+	Cases    []CaseResult `json:"cases"`
+	Passed   int          `json:"passed"`
+	Failed   int          `json:"failed"`
+	Errors   int          `json:"errors"`
+	Accuracy float64      `json:"accuracy"`
+	// Confusion counts, by expected intent, the intents that were recognized.
+	Confusion map[string]map[string]int `json:"confusion"`
+	Duration  time.Duration             `json:"duration"`
+}
+
+// RunRegressionSuite sends every case of the suite to the agent, each in its
+// own session, with at most parallelism cases at the same time.
+func RunRegressionSuite(q Querier, suite *RegressionSuite, parallelism int) *RegressionReport {
+	start := time.Now()
+	run := start.UnixNano()
+	report := &RegressionReport{
+		Suite:     suite.Name,
+		Cases:     make([]CaseResult, len(suite.Cases)),
+		Confusion: map[string]map[string]int{},
+	}
+	var mu sync.Mutex
+	forEachParallel(len(suite.Cases), parallelism, func(i int) error {
+		result := runRegressionCase(q, suite.Cases[i], fmt.Sprintf("regression-%d-%d", run, i))
+		mu.Lock()
+		report.Cases[i] = result
+		mu.Unlock()
+		return nil
+	})
+
+	intentCases, intentHits := 0, 0
+	for _, r := range report.Cases {
+		switch {
+		case r.Error != "":
+			report.Errors++
+		case r.Passed():
+			report.Passed++
+		default:
+			report.Failed++
+		}
+		if r.Case.Intent == "" || r.Error != "" {
+			continue
+		}
+		actual := r.Intent
+		if actual == "" {
+			actual = noIntent
+		}
+		if report.Confusion[r.Case.Intent] == nil {
+			report.Confusion[r.Case.Intent] = map[string]int{}
+		}
+		report.Confusion[r.Case.Intent][actual]++
+		intentCases++
+		if r.Intent == r.Case.Intent {
+			intentHits++
+		}
+	}
+	if intentCases > 0 {
+		report.Accuracy = float64(intentHits) / float64(intentCases)
+	}
+	report.Duration = time.Since(start)
+	return report
+}
+
+func runRegressionCase(q Querier, c RegressionCase, sessionId string) CaseResult {
+	start := time.Now()
+	result := CaseResult{Case: c}
+	var resp *QueryResponse
+	var err error
+	for i, text := range append(append([]string{}, c.Before...), c.Query) {
+		resp, err = q.Query(Query{Query: []string{text}, SessionId: sessionId, ResetContexts: i == 0})
+		if err != nil {
+			result.Error = fmt.Sprintf("query %q, %v", text, err)
+			result.Duration = time.Since(start)
+			return result
+		}
+	}
+	result.Duration = time.Since(start)
+	result.Intent = resp.Result.Metadata.IntentName
+	result.Action = resp.Result.Action
+	result.Score = resp.Result.Score
+
+	if c.Intent != "" && c.Intent != result.Intent {
+		result.Diffs = append(result.Diffs, FieldChange{"intent", c.Intent, result.Intent})
+	}
+	if c.Action != "" && c.Action != result.Action {
+		result.Diffs = append(result.Diffs, FieldChange{"action", c.Action, result.Action})
+	}
+	names := make([]string, 0, len(c.Parameters))
+	for name := range c.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		expected := c.Parameters[name]
+		actual, ok := resp.Result.Params[name]
+		if !ok || !paramEqual(expected, actual) {
+			result.Diffs = append(result.Diffs, FieldChange{"parameters." + name, expected, actual})
+		}
+	}
+	for _, name := range c.Contexts {
+		if _, ok := FindContext(resp.Result.Contexts, name); !ok {
+			result.Diffs = append(result.Diffs, FieldChange{"contexts", name, nil})
+		}
+	}
+	return result
+}
+
+// paramEqual compares parameter values leniently: scalars are compared by
+// their text, as api.ai returns most values as strings.
+func paramEqual(expected, actual interface{}) bool {
+	scalar := func(v interface{}) bool {
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			return false
+		}
+		return true
+	}
+	if scalar(expected) && scalar(actual) {
+		return fmt.Sprint(expected) == fmt.Sprint(actual)
+	}
+	a, errA := json.Marshal(expected)
+	b, errB := json.Marshal(actual)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(expected, actual)
+	}
+	var x, y interface{}
+	json.Unmarshal(a, &x)
+	json.Unmarshal(b, &y)
+	return paramEqualJSON(x, y)
+}
+
+func paramEqualJSON(x, y interface{}) bool {
+	switch x := x.(type) {
+	case map[string]interface{}:
+		m, ok := y.(map[string]interface{})
+		if !ok || len(m) != len(x) {
+			return false
+		}
+		for k, v := range x {
+			if !paramEqualJSON(v, m[k]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		s, ok := y.([]interface{})
+		if !ok || len(s) != len(x) {
+			return false
+		}
+		for i := range x {
+			if !paramEqualJSON(x[i], s[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return fmt.Sprint(x) == fmt.Sprint(y)
+}
+
+// ConfusionLabels returns the expected and recognized intents of the
+// confusion matrix, sorted.
+func (r *RegressionReport) ConfusionLabels() []string {
+	var labels []string
+	for expected, row := range r.Confusion {
+		labels = append(labels, expected)
+		for actual := range row {
+			labels = append(labels, actual)
+		}
+	}
+	sort.Strings(labels)
+	return uniqueStrings(labels)
+}
+
+func (r *RegressionReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML, for CI servers.
+func (r *RegressionReport) WriteJUnit(w io.Writer) error {
+	suite := junitSuite{
+		Name:     r.Suite,
+		Tests:    len(r.Cases),
+		Failures: r.Failed,
+		Errors:   r.Errors,
+		Time:     junitTime(r.Duration),
+	}
+	for _, c := range r.Cases {
+		jc := junitCase{Name: c.Case.Name, ClassName: r.Suite, Time: junitTime(c.Duration)}
+		switch {
+		case c.Error != "":
+			jc.Error = &junitMessage{Message: c.Error}
+		case len(c.Diffs) > 0:
+			lines := make([]string, len(c.Diffs))
+			for i, d := range c.Diffs {
+				lines[i] = fmt.Sprintf("%s: expected %s, got %s", d.Field, formatField(d.From), formatField(d.To))
+			}
+			jc.Failure = &junitMessage{Message: fmt.Sprintf("%d expectations failed", len(c.Diffs)), Text: strings.Join(lines, "\n")}
+		}
+		suite.Cases = append(suite.Cases, jc)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
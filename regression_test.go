@@ -0,0 +1,130 @@
+package apiai
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type queryFunc func(Query) (*QueryResponse, error)
+
+func (f queryFunc) Query(q Query) (*QueryResponse, error) {
+	return f(q)
+}
+
+func TestRunRegressionSuite(t *testing.T) {
+	assert := assert.New(t)
+
+	suite, err := LoadRegressionSuite(strings.NewReader(`
+name: ordering
+cases:
+  - query: hello
+    intent: greet
+  - name: order with size
+    query: a large coffee
+    intent: order
+    action: order.create
+    parameters:
+      drink: coffee
+      quantity: 1
+      size: {name: large}
+    contexts: [ordering]
+  - query: yes
+    before: [a coffee]
+    intent: confirm
+  - query: boom
+    intent: greet
+`))
+	assert.Nil(err)
+	assert.Equal("hello", suite.Cases[0].Name)
+
+	var mu sync.Mutex
+	sessions := map[string][]Query{}
+	q := queryFunc(func(q Query) (*QueryResponse, error) {
+		mu.Lock()
+		sessions[q.SessionId] = append(sessions[q.SessionId], q)
+		mu.Unlock()
+		r := &QueryResponse{}
+		switch q.Query[0] {
+		case "hello":
+			r.Result.Metadata.IntentName = "greet"
+		case "a large coffee":
+			r.Result.Metadata.IntentName = "order"
+			r.Result.Action = "order.create"
+			r.Result.Params = map[string]interface{}{"drink": "coffee", "quantity": "1", "size": map[string]interface{}{"name": "small"}}
+		case "yes":
+			r.Result.Metadata.IntentName = "greet"
+		case "boom":
+			return nil, errors.New("timeout")
+		}
+		return r, nil
+	})
+
+	report := RunRegressionSuite(q, suite, 2)
+	assert.Equal(1, report.Passed)
+	assert.Equal(2, report.Failed)
+	assert.Equal(1, report.Errors)
+	assert.Equal(2.0/3, report.Accuracy)
+	assert.Equal(map[string]map[string]int{
+		"greet":   {"greet": 1},
+		"order":   {"order": 1},
+		"confirm": {"greet": 1},
+	}, report.Confusion)
+	assert.Equal([]string{"confirm", "greet", "order"}, report.ConfusionLabels())
+	assert.Equal([]FieldChange{
+		{"parameters.size", map[string]interface{}{"name": "large"}, map[string]interface{}{"name": "small"}},
+		{"contexts", "ordering", nil},
+	}, report.Cases[1].Diffs)
+	assert.Equal(`query "boom", timeout`, report.Cases[3].Error)
+
+	assert.Len(sessions, 4)
+	for _, queries := range sessions {
+		assert.True(queries[0].ResetContexts)
+		if queries[0].Query[0] == "a coffee" {
+			assert.Equal("yes", queries[1].Query[0])
+			assert.False(queries[1].ResetContexts)
+		}
+	}
+
+	for i := range report.Cases {
+		report.Cases[i].Duration = 0
+	}
+	report.Duration = 0
+	buf := new(bytes.Buffer)
+	assert.Nil(report.WriteJUnit(buf))
+	assert.Equal(`<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="ordering" tests="4" failures="2" errors="1" time="0.000">
+  <testcase name="hello" classname="ordering" time="0.000"></testcase>
+  <testcase name="order with size" classname="ordering" time="0.000">
+    <failure message="2 expectations failed">parameters.size: expected {&#34;name&#34;:&#34;large&#34;}, got {&#34;name&#34;:&#34;small&#34;}&#xA;contexts: expected &#34;ordering&#34;, got null</failure>
+  </testcase>
+  <testcase name="yes" classname="ordering" time="0.000">
+    <failure message="1 expectations failed">intent: expected &#34;confirm&#34;, got &#34;greet&#34;</failure>
+  </testcase>
+  <testcase name="boom" classname="ordering" time="0.000">
+    <error message="query &#34;boom&#34;, timeout"></error>
+  </testcase>
+</testsuite>
+`, buf.String())
+
+	buf.Reset()
+	assert.Nil(report.WriteJSON(buf))
+	assert.Contains(buf.String(), `"diffs": [
+        {
+          "field": "intent",
+          "from": "confirm",
+          "to": "greet"
+        }
+      ]`)
+}
+
+func TestLoadRegressionSuiteErrors(t *testing.T) {
+	_, err := LoadRegressionSuite(strings.NewReader("cases:\n  - intent: greet\n"))
+	assert.EqualError(t, err, "apiai: invalid regression suite, case 1 has no query")
+	_, err = LoadRegressionSuite(strings.NewReader("cases:\n  - query: hi\n    intnet: greet\n"))
+	assert.Error(t, err)
+}
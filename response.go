@@ -24,7 +24,7 @@ func (c *ApiClient) getApiaiResponse(method string, path string, params map[stri
 	req.Header.Set("Authorization", "Bearer "+c.config.Token)
 
 	httpClient := http.DefaultClient
-	resp, err := httpClient.Do(req)
+	resp, err := c.doRequest(httpClient, req, path)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,156 @@
+package apiai
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const clarifyAction = "input.clarify"
+
+// Clarification answers a query whose intent was recognized with too low a
+// score, typically asking the user to rephrase or confirm.
+type Clarification func(q Query, resp *QueryResponse) (*QueryResponse, error)
+
+// ClarifyWithEvent sends event to the agent in the same session, with the
+// query, the guessed intent and its score as event data, so the agent can
+// handle the clarification.
+func ClarifyWithEvent(q Querier, event string) Clarification {
+	return func(query Query, resp *QueryResponse) (*QueryResponse, error) {
+		return q.Query(Query{
+			SessionId: query.SessionId,
+			Event: Event{Name: event, Data: map[string]string{
+				"query":  strings.Join(query.Query, " "),
+				"intent": resp.Result.Metadata.IntentName,
+				"score":  strconv.FormatFloat(resp.Result.Score, 'f', -1, 64),
+			}},
+		})
+	}
+}
+
+// ClarifyWithSpeech answers with speech and the input.clarify action. The
+// guessed intent is kept in the metadata.
+func ClarifyWithSpeech(speech string) Clarification {
+	return func(q Query, resp *QueryResponse) (*QueryResponse, error) {
+		clarified := *resp
+		clarified.Result.Action = clarifyAction
+		clarified.Result.Fulfillment = Fulfilment{Speech: speech, Messages: []Message{{Type: 0, Speech: speech}}}
+		return &clarified, nil
+	}
+}
+
+// LowConfidence is a query recognized with a score under its threshold.
+type LowConfidence struct {
+	Time      time.Time `json:"time"`
+	SessionId string    `json:"sessionId"`
+	Query     string    `json:"query"`
+	Intent    string    `json:"intent"`
+	Score     float64   `json:"score"`
+	MinScore  float64   `json:"minScore"`
+}
+
+// ReviewQueue collects low confidence queries to review them and add them as
+// training phrases.
+type ReviewQueue interface {
+	Add(LowConfidence) error
+}
+
+type MemoryReviewQueue struct {
+	mu    sync.Mutex
+	items []LowConfidence
+}
+
+func (r *MemoryReviewQueue) Add(item LowConfidence) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items = append(r.items, item)
+	return nil
+}
+
+// Drain returns the queued items and empties the queue.
+func (r *MemoryReviewQueue) Drain() []LowConfidence {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	items := r.items
+	r.items = nil
+	return items
+}
+
+// JSONReviewQueue writes every item as a line of JSON.
+type JSONReviewQueue struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewJSONReviewQueue(w io.Writer) *JSONReviewQueue {
+	return &JSONReviewQueue{enc: json.NewEncoder(w)}
+}
+
+func (r *JSONReviewQueue) Add(item LowConfidence) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enc.Encode(item)
+}
+
+// ConfidenceRouter wraps a Querier and acts on the score of the recognized
+// intents. Answers under the minimum score of their intent are clarified and
+// the queries are added to the review queue. Queries that match no intent are
+// only reviewed. Events are passed through.
+type ConfidenceRouter struct {
+	Querier Querier
+	// MinScore applies to the intents not in IntentScores.
+	MinScore     float64
+	IntentScores map[string]float64
+	// Clarify answers low confidence queries, when set. Otherwise the answer
+	// of the agent is returned.
+	Clarify Clarification
+	Review  ReviewQueue
+	// OnReviewError is called, if set, when adding to the review queue fails,
+	// which doesn't fail the query.
+	OnReviewError func(error)
+}
+
+func NewConfidenceRouter(q Querier, minScore float64) *ConfidenceRouter {
+	return &ConfidenceRouter{Querier: q, MinScore: minScore, IntentScores: map[string]float64{}}
+}
+
+// MinScoreFor returns the minimum score of an intent.
+func (r *ConfidenceRouter) MinScoreFor(intent string) float64 {
+	if score, ok := r.IntentScores[intent]; ok {
+		return score
+	}
+	return r.MinScore
+}
+
+func (r *ConfidenceRouter) Query(q Query) (*QueryResponse, error) {
+	resp, err := r.Querier.Query(q)
+	if err != nil || q.Event.Name != "" {
+		return resp, err
+	}
+	intent := resp.Result.Metadata.IntentName
+	minScore := r.MinScoreFor(intent)
+	if intent != "" && resp.Result.Score >= minScore {
+		return resp, nil
+	}
+
+	if r.Review != nil {
+		err := r.Review.Add(LowConfidence{
+			Time:      time.Now().UTC(),
+			SessionId: q.SessionId,
+			Query:     strings.Join(q.Query, " "),
+			Intent:    intent,
+			Score:     resp.Result.Score,
+			MinScore:  minScore,
+		})
+		if err != nil && r.OnReviewError != nil {
+			r.OnReviewError(err)
+		}
+	}
+	if intent == "" || r.Clarify == nil {
+		return resp, nil
+	}
+	return r.Clarify(q, resp)
+}
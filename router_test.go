@@ -0,0 +1,81 @@
+package apiai
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfidenceRouter(t *testing.T) {
+	assert := assert.New(t)
+
+	var events []Event
+	agent := queryFunc(func(q Query) (*QueryResponse, error) {
+		resp := &QueryResponse{}
+		if q.Event.Name != "" {
+			events = append(events, q.Event)
+			resp.Result.Action = "clarify"
+			return resp, nil
+		}
+		switch q.Query[0] {
+		case "a coffee":
+			resp.Result.Metadata.IntentName, resp.Result.Score = "order", 0.6
+		case "cancel it":
+			resp.Result.Metadata.IntentName, resp.Result.Score = "cancel", 0.6
+		case "boom":
+			return nil, errors.New("timeout")
+		default:
+			resp.Result.Action = UnknownInputAction
+		}
+		return resp, nil
+	})
+
+	review := &MemoryReviewQueue{}
+	r := NewConfidenceRouter(agent, 0.5)
+	r.IntentScores["cancel"] = 0.8
+	r.Review = review
+
+	resp, err := r.Query(Query{Query: []string{"a coffee"}, SessionId: "s1"})
+	assert.Nil(err)
+	assert.Equal("order", resp.Result.Metadata.IntentName)
+
+	resp, err = r.Query(Query{Query: []string{"cancel it"}, SessionId: "s1"})
+	assert.Nil(err)
+	assert.Equal("", resp.Result.Action, "without clarification the answer is kept")
+
+	r.Clarify = ClarifyWithSpeech("Sorry, could you say that again?")
+	resp, err = r.Query(Query{Query: []string{"cancel it"}, SessionId: "s1"})
+	assert.Nil(err)
+	assert.Equal(clarifyAction, resp.Result.Action)
+	assert.Equal("cancel", resp.Result.Metadata.IntentName)
+	assert.Equal("Sorry, could you say that again?", resp.Result.Fulfillment.Speech)
+
+	r.Clarify = ClarifyWithEvent(agent, "CLARIFY")
+	resp, err = r.Query(Query{Query: []string{"cancel it"}, SessionId: "s1"})
+	assert.Nil(err)
+	assert.Equal("clarify", resp.Result.Action)
+	assert.Equal([]Event{{Name: "CLARIFY", Data: map[string]string{"query": "cancel it", "intent": "cancel", "score": "0.6"}}}, events)
+
+	resp, err = r.Query(Query{Query: []string{"what?"}, SessionId: "s2"})
+	assert.Nil(err)
+	assert.Equal(UnknownInputAction, resp.Result.Action, "unmatched queries aren't clarified")
+
+	_, err = r.Query(Query{Query: []string{"boom"}})
+	assert.EqualError(err, "timeout")
+
+	items := review.Drain()
+	assert.Len(items, 4)
+	assert.Equal("cancel it", items[0].Query)
+	assert.Equal(0.8, items[0].MinScore)
+	assert.Equal("", items[3].Intent)
+	assert.Empty(review.Drain())
+}
+
+func TestJSONReviewQueue(t *testing.T) {
+	buf := new(bytes.Buffer)
+	q := NewJSONReviewQueue(buf)
+	assert.Nil(t, q.Add(LowConfidence{SessionId: "s1", Query: "a coffee", Intent: "order", Score: 0.25, MinScore: 0.5}))
+	assert.Equal(t, `{"time":"0001-01-01T00:00:00Z","sessionId":"s1","query":"a coffee","intent":"order","score":0.25,"minScore":0.5}`+"\n", buf.String())
+}
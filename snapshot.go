@@ -0,0 +1,338 @@
+package apiai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const snapshotVersion = 1
+
+const snapshotFile = "snapshot.json"
+
+// AgentSnapshot holds the full definition of every intent and entity of an
+// agent.
+type AgentSnapshot struct {
+	Version  int
+	Intents  []Intent
+	Entities []Entity
+}
+
+type snapshotManifest struct {
+	Version  int `json:"version"`
+	Intents  int `json:"intents"`
+	Entities int `json:"entities"`
+}
+
+// Snapshot fetches every intent and entity of the agent, with at most
+// parallelism requests at the same time.
+func (c *ApiClient) Snapshot(parallelism int) (*AgentSnapshot, error) {
+	intents, err := c.GetIntents()
+	if err != nil {
+		return nil, err
+	}
+	entities, err := c.GetEntities()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &AgentSnapshot{
+		Version:  snapshotVersion,
+		Intents:  make([]Intent, len(intents)),
+		Entities: make([]Entity, len(entities)),
+	}
+	err = forEachParallel(len(intents)+len(entities), parallelism, func(i int) error {
+		if i < len(intents) {
+			intent, err := c.GetIntent(intents[i].Id)
+			if err != nil {
+				return fmt.Errorf("apiai: error on intent %q, %w", intents[i].Name, err)
+			}
+			s.Intents[i] = *intent
+			return nil
+		}
+		i -= len(intents)
+		entity, err := c.GetEntity(entities[i].Id)
+		if err != nil {
+			return fmt.Errorf("apiai: error on entity %q, %w", entities[i].Name, err)
+		}
+		s.Entities[i] = *entity
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(s.Intents, func(i, j int) bool { return s.Intents[i].Name < s.Intents[j].Name })
+	sort.Slice(s.Entities, func(i, j int) bool { return s.Entities[i].Name < s.Entities[j].Name })
+	return s, nil
+}
+
+// Save writes the snapshot to dir, one indented JSON file per intent and
+// entity, so that snapshots of the same agent are identical and diff well.
+// Files of intents and entities no longer in the snapshot are removed.
+func (s *AgentSnapshot) Save(dir string) error {
+	for _, sub := range []string{intentsDir, entitiesDir} {
+		if err := os.RemoveAll(filepath.Join(dir, sub)); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return err
+		}
+	}
+	manifest := snapshotManifest{Version: snapshotVersion, Intents: len(s.Intents), Entities: len(s.Entities)}
+	if err := writeJSONFile(filepath.Join(dir, snapshotFile), manifest); err != nil {
+		return err
+	}
+	intentNames := make([]string, len(s.Intents))
+	for i, intent := range s.Intents {
+		intentNames[i] = intent.Name
+	}
+	intentFiles, err := snapshotFileNames(intentNames)
+	if err != nil {
+		return err
+	}
+	for i, intent := range s.Intents {
+		if err := writeJSONFile(filepath.Join(dir, intentsDir, intentFiles[i]), intent); err != nil {
+			return err
+		}
+	}
+	entityNames := make([]string, len(s.Entities))
+	for i, entity := range s.Entities {
+		entityNames[i] = entity.Name
+	}
+	entityFiles, err := snapshotFileNames(entityNames)
+	if err != nil {
+		return err
+	}
+	for i, entity := range s.Entities {
+		if err := writeJSONFile(filepath.Join(dir, entitiesDir, entityFiles[i]), entity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotFileNames returns the file name for each of names. Names that map to
+// the same file, also on case-insensitive file systems, get a short hash of the
+// name so that none is overwritten.
+func snapshotFileNames(names []string) ([]string, error) {
+	count := map[string]int{}
+	for _, name := range names {
+		count[strings.ToLower(archiveFileName(name))]++
+	}
+	files := make([]string, len(names))
+	used := map[string]string{}
+	for i, name := range names {
+		file := archiveFileName(name)
+		if count[strings.ToLower(file)] > 1 {
+			sum := sha256.Sum256([]byte(name))
+			file += "-" + hex.EncodeToString(sum[:4])
+		}
+		file += ".json"
+		if other, ok := used[strings.ToLower(file)]; ok {
+			return nil, fmt.Errorf("apiai: %q and %q have the same snapshot file name %s", other, name, file)
+		}
+		used[strings.ToLower(file)] = name
+		files[i] = file
+	}
+	return files, nil
+}
+
+func LoadAgentSnapshot(dir string) (*AgentSnapshot, error) {
+	var manifest snapshotManifest
+	if err := readJSONFile(filepath.Join(dir, snapshotFile), &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Version != snapshotVersion {
+		return nil, fmt.Errorf("apiai: unsupported snapshot version %d", manifest.Version)
+	}
+	s := &AgentSnapshot{Version: manifest.Version}
+	intentFiles, err := snapshotFiles(filepath.Join(dir, intentsDir))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range intentFiles {
+		var intent Intent
+		if err := readJSONFile(f, &intent); err != nil {
+			return nil, err
+		}
+		s.Intents = append(s.Intents, intent)
+	}
+	entityFiles, err := snapshotFiles(filepath.Join(dir, entitiesDir))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range entityFiles {
+		var entity Entity
+		if err := readJSONFile(f, &entity); err != nil {
+			return nil, err
+		}
+		s.Entities = append(s.Entities, entity)
+	}
+	if len(s.Intents) != manifest.Intents || len(s.Entities) != manifest.Entities {
+		return nil, fmt.Errorf("apiai: snapshot in %s is incomplete", dir)
+	}
+	sort.Slice(s.Intents, func(i, j int) bool { return s.Intents[i].Name < s.Intents[j].Name })
+	sort.Slice(s.Entities, func(i, j int) bool { return s.Entities[i].Name < s.Entities[j].Name })
+	return s, nil
+}
+
+func snapshotFiles(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var files []string
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), ".json") {
+			files = append(files, filepath.Join(dir, info.Name()))
+		}
+	}
+	return files, nil
+}
+
+func writeJSONFile(name string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, append(b, '\n'), 0644)
+}
+
+func readJSONFile(name string, v interface{}) error {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("apiai: invalid %s, %v", name, err)
+	}
+	return nil
+}
+
+type RestoreResult struct {
+	// Ids maps the ids in the snapshot to the ids in the restored agent.
+	Ids     map[string]string
+	Created int
+	Updated int
+}
+
+// Restore recreates the snapshot in the agent. Intents and entities are matched
+// by name: existing ones are updated and missing ones are created, so the
+// agent can be empty. Intents and entities not in the snapshot are kept.
+func (c *ApiClient) Restore(s *AgentSnapshot) (*RestoreResult, error) {
+	result := &RestoreResult{Ids: map[string]string{}}
+
+	remoteEntities, err := c.GetEntities()
+	if err != nil {
+		return nil, err
+	}
+	entityIds := map[string]string{}
+	for _, e := range remoteEntities {
+		entityIds[e.Name] = e.Id
+	}
+	entities, err := ResolveEntityDependencies(s.Entities)
+	if err != nil {
+		return nil, err
+	}
+	for _, entity := range entities {
+		oldId := entity.Id
+		if id, ok := entityIds[entity.Name]; ok {
+			entity.Id = id
+			if err := c.UpdateEntity(id, entity); err != nil {
+				return result, fmt.Errorf("apiai: error on entity %q, %w", entity.Name, err)
+			}
+			result.Updated++
+		} else {
+			entity.Id = ""
+			cr, err := c.CreateEntity(entity)
+			if err != nil {
+				return result, fmt.Errorf("apiai: error on entity %q, %w", entity.Name, err)
+			}
+			entity.Id = cr.Id
+			result.Created++
+		}
+		if oldId != "" {
+			result.Ids[oldId] = entity.Id
+		}
+	}
+
+	remoteIntents, err := c.GetIntents()
+	if err != nil {
+		return result, err
+	}
+	intentIds := map[string]string{}
+	for _, i := range remoteIntents {
+		intentIds[i.Name] = i.Id
+	}
+	for _, intent := range s.Intents {
+		oldId := intent.Id
+		intent = withoutUserSaysIds(intent)
+		if id, ok := intentIds[intent.Name]; ok {
+			intent.Id = id
+			if err := c.UpdateIntent(id, intent); err != nil {
+				return result, fmt.Errorf("apiai: error on intent %q, %w", intent.Name, err)
+			}
+			result.Updated++
+		} else {
+			intent.Id = ""
+			cr, err := c.CreateIntent(intent)
+			if err != nil {
+				return result, fmt.Errorf("apiai: error on intent %q, %w", intent.Name, err)
+			}
+			intent.Id = cr.Id
+			result.Created++
+		}
+		if oldId != "" {
+			result.Ids[oldId] = intent.Id
+		}
+	}
+	return result, nil
+}
+
+// forEachParallel calls fn for 0 to n-1 with at most parallelism concurrent
+// calls, and returns the first error.
+func forEachParallel(n, parallelism int, fn func(i int) error) error {
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	work := make(chan int)
+	for w := 0; w < parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				if err := fn(i); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,133 @@
+package apiai
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	c, err := NewClient(&ClientConfig{Token: "fakeToken"})
+	if err != nil {
+		t.FailNow()
+	}
+	assert := assert.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", c.buildUrl("intents", nil), httpmock.NewStringResponder(200, `[
+  {"id": "i2", "name": "order"},
+  {"id": "i1", "name": "greet"}
+]`))
+	httpmock.RegisterResponder("GET", c.buildUrl("entities", nil), httpmock.NewStringResponder(200, `[
+  {"id": "e1", "name": "drink"}
+]`))
+	httpmock.RegisterResponder("GET", c.buildUrl("intents/i1", nil), httpmock.NewStringResponder(200, `{"id": "i1", "name": "greet", "userSays": [{"id": "u1", "data": [{"text": "hi"}]}]}`))
+	httpmock.RegisterResponder("GET", c.buildUrl("intents/i2", nil), httpmock.NewStringResponder(200, `{"id": "i2", "name": "order", "responses": [{"action": "order.create"}]}`))
+	httpmock.RegisterResponder("GET", c.buildUrl("entities/e1", nil), httpmock.NewStringResponder(200, `{"id": "e1", "name": "drink", "entries": [{"value": "coffee", "synonyms": ["coffee"]}]}`))
+
+	s, err := c.Snapshot(4)
+	assert.Nil(err)
+	assert.Equal(snapshotVersion, s.Version)
+	assert.Equal("greet", s.Intents[0].Name)
+	assert.Equal("order", s.Intents[1].Name)
+	assert.Equal("coffee", s.Entities[0].Entries[0].Value)
+
+	dir, err := ioutil.TempDir("", "apiai-snapshot")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "intents"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "intents", "stale.json"), []byte(`{}`), 0644)
+
+	assert.Nil(s.Save(dir))
+	first, _ := ioutil.ReadFile(filepath.Join(dir, "intents", "greet.json"))
+	_, err = os.Stat(filepath.Join(dir, "intents", "stale.json"))
+	assert.True(os.IsNotExist(err), "stale files are removed")
+
+	loaded, err := LoadAgentSnapshot(dir)
+	assert.Nil(err)
+	assert.Equal(s, loaded)
+
+	assert.Nil(loaded.Save(dir))
+	second, _ := ioutil.ReadFile(filepath.Join(dir, "intents", "greet.json"))
+	assert.Equal(first, second, "snapshots are deterministic")
+
+	httpmock.RegisterResponder("GET", c.buildUrl("entities/e1", nil), httpmock.NewStringResponder(http.StatusInternalServerError, `{}`))
+	_, err = c.Snapshot(2)
+	assert.EqualError(err, `apiai: error on entity "drink", apiai: wops something happens because status code is 500`)
+}
+
+func TestSnapshotFileNameCollision(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "apiai-snapshot")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	s := &AgentSnapshot{
+		Version: snapshotVersion,
+		Intents: []Intent{{Id: "i1", Name: "Order"}, {Id: "i2", Name: "a/b"}, {Id: "i3", Name: "a_b"}, {Id: "i4", Name: "order"}},
+	}
+	assert.Nil(s.Save(dir))
+	files, err := snapshotFiles(filepath.Join(dir, "intents"))
+	assert.Nil(err)
+	assert.Len(files, 4, "colliding names get different files")
+
+	loaded, err := LoadAgentSnapshot(dir)
+	assert.Nil(err)
+	assert.Equal(s, loaded)
+}
+
+func TestRestore(t *testing.T) {
+	c, err := NewClient(&ClientConfig{Token: "fakeToken"})
+	if err != nil {
+		t.FailNow()
+	}
+	assert := assert.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", c.buildUrl("entities", nil), httpmock.NewStringResponder(200, `[]`))
+	httpmock.RegisterResponder("GET", c.buildUrl("intents", nil), httpmock.NewStringResponder(200, `[{"id": "x1", "name": "greet"}]`))
+
+	var mu sync.Mutex
+	var created []map[string]interface{}
+	httpmock.RegisterResponder("POST", c.buildUrl("entities", nil), func(req *http.Request) (*http.Response, error) {
+		return httpmock.NewStringResponse(200, `{"id": "new-e1", "status": {"code": 200}}`), nil
+	})
+	httpmock.RegisterResponder("POST", c.buildUrl("intents", nil), func(req *http.Request) (*http.Response, error) {
+		b, _ := ioutil.ReadAll(req.Body)
+		var body map[string]interface{}
+		json.Unmarshal(b, &body)
+		mu.Lock()
+		created = append(created, body)
+		mu.Unlock()
+		return httpmock.NewStringResponse(200, `{"id": "new-i2", "status": {"code": 200}}`), nil
+	})
+	httpmock.RegisterResponder("PUT", c.buildUrl("intents/x1", nil), httpmock.NewStringResponder(200, `{}`))
+
+	result, err := c.Restore(&AgentSnapshot{
+		Version: snapshotVersion,
+		Intents: []Intent{
+			{Id: "i1", Name: "greet"},
+			{Id: "i2", Name: "order", UserSays: []UserSays{{Id: "u1", Data: []Data{{Text: "a coffee"}}}}},
+		},
+		Entities: []Entity{{Id: "e1", Name: "drink"}},
+	})
+
+	assert.Nil(err)
+	assert.Equal(&RestoreResult{
+		Ids:     map[string]string{"e1": "new-e1", "i1": "x1", "i2": "new-i2"},
+		Created: 2,
+		Updated: 1,
+	}, result)
+	assert.Len(created, 1)
+	assert.Equal("", created[0]["id"])
+	assert.Equal("", created[0]["userSays"].([]interface{})[0].(map[string]interface{})["id"])
+}
@@ -0,0 +1,266 @@
+package apiai
+
+import (
+	"strings"
+	"unicode"
+)
+
+var diacritics = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ą': "a",
+	'ç': "c", 'ć': "c", 'č': "c",
+	'ď': "d", 'đ': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ę': "e", 'ě': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i",
+	'ł': "l",
+	'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o",
+	'ř': "r",
+	'ś': "s", 'š': "s", 'ß': "ss",
+	'ť': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u",
+	'ý': "y", 'ÿ': "y",
+	'ź': "z", 'ż': "z", 'ž': "z",
+	'æ': "ae", 'œ': "oe",
+}
+
+// StripDiacritics replaces accented latin letters by their base letter.
+func StripDiacritics(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		lower := unicode.ToLower(r)
+		if base, ok := diacritics[lower]; ok {
+			if lower != r {
+				base = strings.ToUpper(base)
+			}
+			b.WriteString(base)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// NormalizeSynonym folds case, diacritics, hyphens and repeated spaces so
+// synonyms that api.ai would match the same way compare equal.
+func NormalizeSynonym(s string) string {
+	s = strings.ToLower(StripDiacritics(s))
+	s = strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' {
+			return ' '
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// SynonymExpander generates synonym variants for a query language.
+type SynonymExpander struct {
+	Lang       string
+	Case       bool
+	Plurals    bool
+	Diacritics bool
+	Separators bool
+}
+
+// NewSynonymExpander returns an expander generating every kind of variant.
+func NewSynonymExpander(lang string) *SynonymExpander {
+	return &SynonymExpander{Lang: lang, Case: true, Plurals: true, Diacritics: true, Separators: true}
+}
+
+// Expand returns synonym followed by its variants, without duplicates.
+func (e *SynonymExpander) Expand(synonym string) []string {
+	variants := []string{synonym}
+	add := func(fn func(string) []string) {
+		for _, v := range variants {
+			variants = append(variants, fn(v)...)
+		}
+		variants = uniqueStrings(variants)
+	}
+	if e.Case {
+		add(func(s string) []string { return []string{strings.ToLower(s)} })
+	}
+	if e.Separators {
+		add(separatorVariants)
+	}
+	if e.Diacritics {
+		add(func(s string) []string { return []string{StripDiacritics(s)} })
+	}
+	if e.Plurals {
+		add(func(s string) []string { return inflections(e.Lang, s) })
+	}
+	return variants
+}
+
+// ExpandEntries returns a copy of entries with the variants of every synonym.
+func (e *SynonymExpander) ExpandEntries(entries []Entry) []Entry {
+	expanded := make([]Entry, len(entries))
+	for i, entry := range entries {
+		var synonyms []string
+		for _, s := range entry.Synonyms {
+			synonyms = append(synonyms, e.Expand(s)...)
+		}
+		expanded[i] = Entry{Value: entry.Value, Synonyms: uniqueStrings(synonyms)}
+	}
+	return expanded
+}
+
+func separatorVariants(s string) []string {
+	if !strings.ContainsAny(s, " -") {
+		return nil
+	}
+	words := strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == '-' })
+	return []string{strings.Join(words, " "), strings.Join(words, "-"), strings.Join(words, "")}
+}
+
+// inflections returns the plural or singular of the last word of s. Only
+// regular forms of English, Spanish, Portuguese and French are known, and for
+// Italian the plural of regular nouns ending in -o and -a, since the singular
+// of other endings is ambiguous.
+func inflections(lang, s string) []string {
+	i := strings.LastIndexAny(s, " -") + 1
+	prefix, word := s[:i], s[i:]
+	if len([]rune(word)) < 3 {
+		return nil
+	}
+	lower := strings.ToLower(word)
+	// Suffixes take the case of the word, CITIES -> CITY.
+	upper := word != lower && word == strings.ToUpper(word)
+	suffix := func(s string) string {
+		if upper {
+			return strings.ToUpper(s)
+		}
+		return s
+	}
+	var forms []string
+	base := strings.SplitN(lang, "-", 2)[0]
+	switch base {
+	case "en":
+		switch {
+		case strings.HasSuffix(lower, "ies"):
+			forms = []string{word[:len(word)-3] + suffix("y")}
+		case strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"), strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "sses"):
+			forms = []string{word[:len(word)-2]}
+		case strings.HasSuffix(lower, "ss"):
+			forms = []string{word + suffix("es")}
+		case strings.HasSuffix(lower, "s"):
+			forms = []string{word[:len(word)-1]}
+		case strings.HasSuffix(lower, "y") && !strings.ContainsAny(lower[len(lower)-2:len(lower)-1], "aeiou"):
+			forms = []string{word[:len(word)-1] + suffix("ies")}
+		case strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"), strings.HasSuffix(lower, "x"):
+			forms = []string{word + suffix("es")}
+		default:
+			forms = []string{word + suffix("s")}
+		}
+	case "it":
+		switch {
+		case strings.HasSuffix(lower, "co"), strings.HasSuffix(lower, "go"):
+			// amico -> amici but lago -> laghi.
+		case strings.HasSuffix(lower, "io"):
+			forms = []string{word[:len(word)-1]}
+		case strings.HasSuffix(lower, "ca"), strings.HasSuffix(lower, "ga"):
+			forms = []string{word[:len(word)-1] + suffix("he")}
+		case strings.HasSuffix(lower, "o"):
+			forms = []string{word[:len(word)-1] + suffix("i")}
+		case strings.HasSuffix(lower, "a"):
+			forms = []string{word[:len(word)-1] + suffix("e")}
+		}
+	case "es", "pt", "fr":
+		runes := []rune(StripDiacritics(lower))
+		last := string(runes[len(runes)-1])
+		switch {
+		case strings.HasSuffix(lower, "es") && base == "es":
+			forms = []string{word[:len(word)-2], word[:len(word)-1]}
+		case last == "s":
+			forms = []string{word[:len(word)-1]}
+		case strings.ContainsAny(last, "aeiou"), base == "fr":
+			forms = []string{word + suffix("s")}
+		case base == "es":
+			forms = []string{word + suffix("es")}
+		}
+	}
+	for i := range forms {
+		forms[i] = prefix + forms[i]
+	}
+	return forms
+}
+
+type SynonymCollision struct {
+	Synonym string
+	Values  []string
+}
+
+// FindSynonymCollisions reports synonyms that, once normalized, belong to more
+// than one entry, so api.ai couldn't tell which value to pick.
+func FindSynonymCollisions(entries []Entry) []SynonymCollision {
+	owners := map[string][]string{}
+	var order []string
+	for _, entry := range entries {
+		seen := map[string]bool{}
+		for _, s := range entry.Synonyms {
+			n := NormalizeSynonym(s)
+			if seen[n] {
+				continue
+			}
+			seen[n] = true
+			if _, ok := owners[n]; !ok {
+				order = append(order, n)
+			}
+			owners[n] = append(owners[n], entry.Value)
+		}
+	}
+	var collisions []SynonymCollision
+	for _, n := range order {
+		if len(owners[n]) > 1 {
+			collisions = append(collisions, SynonymCollision{n, owners[n]})
+		}
+	}
+	return collisions
+}
+
+// DedupeSynonyms removes synonyms repeated within an entry and synonyms already
+// used by a previous entry, comparing them normalized. Synonyms removed because
+// of other entries are reported as collisions.
+func DedupeSynonyms(entries []Entry) ([]Entry, []SynonymCollision) {
+	owner := map[string]string{}
+	collided := map[string][]string{}
+	var order []string
+	deduped := make([]Entry, len(entries))
+	for i, entry := range entries {
+		var synonyms []string
+		for _, s := range entry.Synonyms {
+			n := NormalizeSynonym(s)
+			prev, ok := owner[n]
+			if !ok {
+				owner[n] = entry.Value
+				synonyms = append(synonyms, s)
+				continue
+			}
+			if prev == entry.Value {
+				continue
+			}
+			if _, ok := collided[n]; !ok {
+				order = append(order, n)
+				collided[n] = []string{prev}
+			}
+			collided[n] = uniqueStrings(append(collided[n], entry.Value))
+		}
+		deduped[i] = Entry{Value: entry.Value, Synonyms: synonyms}
+	}
+	collisions := make([]SynonymCollision, 0, len(order))
+	for _, n := range order {
+		collisions = append(collisions, SynonymCollision{n, collided[n]})
+	}
+	return deduped, collisions
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := values[:0:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,114 @@
+package apiai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeSynonym(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("cafe creme", NormalizeSynonym("Café  Crème"))
+	assert.Equal("coffee maker", NormalizeSynonym("Coffee-Maker"))
+	assert.Equal("Strasse", StripDiacritics("Straße"))
+	assert.Equal("Ecole", StripDiacritics("École"))
+}
+
+func TestSynonymExpander(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		description      string
+		expander         *SynonymExpander
+		synonym          string
+		expectedResponse []string
+	}{
+		{
+			description:      "english plural and separators",
+			expander:         NewSynonymExpander("en"),
+			synonym:          "Coffee-Maker",
+			expectedResponse: []string{"Coffee-Maker", "coffee-maker", "Coffee Maker", "CoffeeMaker", "coffee maker", "coffeemaker", "Coffee-Makers", "coffee-makers", "Coffee Makers", "CoffeeMakers", "coffee makers", "coffeemakers"},
+		}, {
+			description:      "english singular",
+			expander:         &SynonymExpander{Lang: "en", Plurals: true},
+			synonym:          "batteries",
+			expectedResponse: []string{"batteries", "battery"},
+		}, {
+			description:      "uppercase words get uppercase suffixes",
+			expander:         &SynonymExpander{Lang: "en", Plurals: true},
+			synonym:          "CITIES",
+			expectedResponse: []string{"CITIES", "CITY"},
+		}, {
+			description:      "uppercase spanish plural",
+			expander:         &SynonymExpander{Lang: "es", Plurals: true},
+			synonym:          "CAFÉ",
+			expectedResponse: []string{"CAFÉ", "CAFÉS"},
+		}, {
+			description:      "spanish diacritics and plural",
+			expander:         &SynonymExpander{Lang: "es", Diacritics: true, Plurals: true},
+			synonym:          "café",
+			expectedResponse: []string{"café", "cafe", "cafés", "cafes"},
+		}, {
+			description:      "italian plurals change the last vowel",
+			expander:         &SynonymExpander{Lang: "it", Plurals: true},
+			synonym:          "libro",
+			expectedResponse: []string{"libro", "libri"},
+		}, {
+			description:      "italian feminine plural",
+			expander:         &SynonymExpander{Lang: "it", Plurals: true},
+			synonym:          "casa",
+			expectedResponse: []string{"casa", "case"},
+		}, {
+			description:      "italian words ending in e can be singular or plural",
+			expander:         &SynonymExpander{Lang: "it", Plurals: true},
+			synonym:          "tazza grande",
+			expectedResponse: []string{"tazza grande"},
+		}, {
+			description:      "italian plural after c",
+			expander:         &SynonymExpander{Lang: "it", Plurals: true},
+			synonym:          "banca",
+			expectedResponse: []string{"banca", "banche"},
+		}, {
+			description:      "ambiguous italian endings aren't inflected",
+			expander:         &SynonymExpander{Lang: "it", Plurals: true},
+			synonym:          "cani",
+			expectedResponse: []string{"cani"},
+		}, {
+			description:      "unknown language has no inflections",
+			expander:         &SynonymExpander{Lang: "ja", Plurals: true},
+			synonym:          "coffee",
+			expectedResponse: []string{"coffee"},
+		},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(tc.expectedResponse, tc.expander.Expand(tc.synonym), tc.description)
+	}
+
+	entries := (&SynonymExpander{Lang: "en", Case: true}).ExpandEntries([]Entry{
+		{Value: "Kettle", Synonyms: []string{"Kettle", "kettle"}},
+	})
+	assert.Equal([]Entry{{Value: "Kettle", Synonyms: []string{"Kettle", "kettle"}}}, entries)
+}
+
+func TestSynonymCollisions(t *testing.T) {
+	assert := assert.New(t)
+	entries := []Entry{
+		{Value: "Coffee Maker", Synonyms: []string{"coffee maker", "Coffee-Maker", "machine"}},
+		{Value: "Espresso Machine", Synonyms: []string{"espresso machine", "Machine"}},
+		{Value: "Kettle", Synonyms: []string{"kettle", "machine"}},
+	}
+
+	assert.Equal([]SynonymCollision{
+		{Synonym: "machine", Values: []string{"Coffee Maker", "Espresso Machine", "Kettle"}},
+	}, FindSynonymCollisions(entries))
+
+	deduped, collisions := DedupeSynonyms(entries)
+	assert.Equal([]Entry{
+		{Value: "Coffee Maker", Synonyms: []string{"coffee maker", "machine"}},
+		{Value: "Espresso Machine", Synonyms: []string{"espresso machine"}},
+		{Value: "Kettle", Synonyms: []string{"kettle"}},
+	}, deduped)
+	assert.Equal(FindSynonymCollisions(entries), collisions)
+}
@@ -0,0 +1,275 @@
+package apiai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// RedactionRule replaces the matches of Pattern with [REDACTED:Name]. Valid,
+// when set, filters the matches to redact.
+type RedactionRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Valid   func(match string) bool
+}
+
+var (
+	EmailRule = RedactionRule{
+		Name:    "email",
+		Pattern: regexp.MustCompile(`[\w.+-]+@[\w-]+(?:\.[\w-]+)+`),
+	}
+	PhoneRule = RedactionRule{
+		Name:    "phone",
+		Pattern: regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{2,4}\)[\s.-]?)?\d{2,4}(?:[\s.-]?\d{2,4}){2,3}`),
+		Valid:   func(s string) bool { n := countDigits(s); return n >= 9 && n <= 15 },
+	}
+	// CardNumberRule matches payment card numbers, checking their Luhn digit so
+	// that other long numbers are kept.
+	CardNumberRule = RedactionRule{
+		Name:    "card",
+		Pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		Valid:   luhnValid,
+	}
+)
+
+// DefaultRedactionRules redacts card numbers, emails and phone numbers. Card
+// numbers go first since they would also pass for phone numbers.
+func DefaultRedactionRules() []RedactionRule {
+	return []RedactionRule{CardNumberRule, EmailRule, PhoneRule}
+}
+
+func countDigits(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			n++
+		}
+	}
+	return n
+}
+
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		d := int(s[i] - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// Redactor removes personal data from texts and parameter values.
+type Redactor struct {
+	Rules []RedactionRule
+	// Params are parameters whose values are always redacted, like ones of
+	// type @sys.email.
+	Params []string
+}
+
+func NewRedactor() *Redactor {
+	return &Redactor{Rules: DefaultRedactionRules()}
+}
+
+func (r *Redactor) Redact(s string) string {
+	for _, rule := range r.Rules {
+		s = rule.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if rule.Valid != nil && !rule.Valid(match) {
+				return match
+			}
+			return "[REDACTED:" + rule.Name + "]"
+		})
+	}
+	return s
+}
+
+// RedactParams redacts, in place, the string values of params, including the
+// ones nested in maps and lists.
+func (r *Redactor) RedactParams(params map[string]interface{}) {
+	for k, v := range params {
+		params[k] = r.redactValue(v)
+	}
+	for _, name := range r.Params {
+		if _, ok := params[name]; ok {
+			params[name] = "[REDACTED]"
+		}
+	}
+}
+
+func (r *Redactor) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return r.Redact(v)
+	case map[string]interface{}:
+		r.RedactParams(v)
+	case []interface{}:
+		for i, e := range v {
+			v[i] = r.redactValue(e)
+		}
+	}
+	return v
+}
+
+// TranscriptRecord is a query and its answer, as logged by TranscriptLogger.
+type TranscriptRecord struct {
+	Time      time.Time      `json:"time"`
+	Session   string         `json:"session"`
+	Request   Query          `json:"request"`
+	Response  *QueryResponse `json:"response,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	LatencyMs float64        `json:"latencyMs"`
+}
+
+type TranscriptSink interface {
+	Write(TranscriptRecord) error
+}
+
+// JSONLSink writes every record as a line of JSON.
+type JSONLSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	return &JSONLSink{enc: json.NewEncoder(w)}
+}
+
+func (s *JSONLSink) Write(record TranscriptRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(record)
+}
+
+// TranscriptLogger wraps a Querier and writes every query and answer to Sink,
+// with personal data redacted and the sessionId replaced with a salted hash,
+// so that the turns of a conversation can be grouped but not traced back.
+// The query and answer returned to the caller are left untouched.
+type TranscriptLogger struct {
+	Querier  Querier
+	Sink     TranscriptSink
+	Redactor *Redactor
+	Salt     string
+	// OnError is called, if set, when writing to the sink fails, which doesn't
+	// fail the query.
+	OnError func(error)
+}
+
+func NewTranscriptLogger(q Querier, sink TranscriptSink, salt string) *TranscriptLogger {
+	return &TranscriptLogger{Querier: q, Sink: sink, Redactor: NewRedactor(), Salt: salt}
+}
+
+func (l *TranscriptLogger) Query(q Query) (*QueryResponse, error) {
+	start := time.Now()
+	resp, err := l.Querier.Query(q)
+	record := TranscriptRecord{
+		Time:      start.UTC(),
+		Session:   l.hashSession(q.SessionId),
+		LatencyMs: float64(time.Since(start)) / float64(time.Millisecond),
+	}
+	if err != nil {
+		record.Error = l.Redactor.Redact(err.Error())
+	}
+	if copyErr := l.redact(q, resp, &record); copyErr == nil {
+		if werr := l.Sink.Write(record); werr != nil && l.OnError != nil {
+			l.OnError(werr)
+		}
+	} else if l.OnError != nil {
+		l.OnError(copyErr)
+	}
+	return resp, err
+}
+
+func (l *TranscriptLogger) hashSession(sessionId string) string {
+	sum := sha256.Sum256([]byte(l.Salt + sessionId))
+	return hex.EncodeToString(sum[:16])
+}
+
+// redact sets in record redacted copies of the query and the answer, with
+// every text field of them redacted.
+func (l *TranscriptLogger) redact(q Query, resp *QueryResponse, record *TranscriptRecord) error {
+	if err := deepCopy(q, &record.Request); err != nil {
+		return err
+	}
+	req := &record.Request
+	req.SessionId = record.Session
+	l.redactStrings(req.Query)
+	l.redactStringMap(req.Event.Data)
+	l.redactStringMap(req.OriginalRequest.Data)
+	l.redactContexts(req.Contexts)
+	for _, e := range req.Entities {
+		for i := range e.Entries {
+			e.Entries[i].Value = l.Redactor.Redact(e.Entries[i].Value)
+			l.redactStrings(e.Entries[i].Synonyms)
+		}
+	}
+	if resp == nil {
+		return nil
+	}
+
+	record.Response = &QueryResponse{}
+	if err := deepCopy(resp, record.Response); err != nil {
+		return err
+	}
+	r := &record.Response.Result
+	record.Response.SessionId = record.Session
+	record.Response.Status.ErrorDetails = l.Redactor.Redact(record.Response.Status.ErrorDetails)
+	r.ResolvedQuery = l.Redactor.Redact(r.ResolvedQuery)
+	l.Redactor.RedactParams(r.Params)
+	l.redactContexts(r.Contexts)
+	r.Fulfillment.Speech = l.Redactor.Redact(r.Fulfillment.Speech)
+	for i := range r.Fulfillment.Messages {
+		l.redactMessage(&r.Fulfillment.Messages[i])
+	}
+	return nil
+}
+
+func (l *TranscriptLogger) redactMessage(m *Message) {
+	m.Speech = l.Redactor.Redact(m.Speech)
+	m.ImageUrl = l.Redactor.Redact(m.ImageUrl)
+	m.Title = l.Redactor.Redact(m.Title)
+	m.Subtitle = l.Redactor.Redact(m.Subtitle)
+	l.redactStrings(m.Replies)
+	for i := range m.Buttons {
+		m.Buttons[i].Text = l.Redactor.Redact(m.Buttons[i].Text)
+		m.Buttons[i].Postback = l.Redactor.Redact(m.Buttons[i].Postback)
+	}
+	m.Payload = l.Redactor.redactValue(m.Payload)
+}
+
+func (l *TranscriptLogger) redactContexts(contexts []Context) {
+	for _, c := range contexts {
+		l.Redactor.RedactParams(c.Params)
+	}
+}
+
+func (l *TranscriptLogger) redactStrings(values []string) {
+	for i, v := range values {
+		values[i] = l.Redactor.Redact(v)
+	}
+}
+
+func (l *TranscriptLogger) redactStringMap(values map[string]string) {
+	for k, v := range values {
+		values[k] = l.Redactor.Redact(v)
+	}
+}
+
+func deepCopy(from, to interface{}) error {
+	b, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, to)
+}
@@ -0,0 +1,124 @@
+package apiai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor(t *testing.T) {
+	r := NewRedactor()
+	tests := []struct {
+		description string
+		text        string
+		expected    string
+	}{
+		{"email", "mail me at jane.doe+bot@example.co.uk please", "mail me at [REDACTED:email] please"},
+		{"phone", "call +34 612 345 678 now", "call [REDACTED:phone] now"},
+		{"phone with area code", "it's (555) 123-4567", "it's [REDACTED:phone]"},
+		{"card", "my card is 4111 1111 1111 1111", "my card is [REDACTED:card]"},
+		{"card without separators", "4012888888881881", "[REDACTED:card]"},
+		{"numbers failing luhn are kept", "order 4111 1111 1111 1112 items", "order 4111 1111 1111 1112 items"},
+		{"short numbers are kept", "a table for 4 at 8:30 on 2017-05-12", "a table for 4 at 8:30 on 2017-05-12"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, r.Redact(test.text), test.description)
+	}
+
+	r.Rules = append(r.Rules, RedactionRule{Name: "iban", Pattern: regexp.MustCompile(`ES\d{22}`)})
+	r.Params = []string{"name"}
+	params := map[string]interface{}{
+		"name":    "Jane",
+		"contact": map[string]interface{}{"email": "jane@example.com", "tags": []interface{}{"ES9121000418450200051332", 3.0}},
+	}
+	r.RedactParams(params)
+	assert.Equal(t, map[string]interface{}{
+		"name":    "[REDACTED]",
+		"contact": map[string]interface{}{"email": "[REDACTED:email]", "tags": []interface{}{"[REDACTED:iban]", 3.0}},
+	}, params)
+}
+
+type memorySink []TranscriptRecord
+
+func (s *memorySink) Write(r TranscriptRecord) error {
+	*s = append(*s, r)
+	return nil
+}
+
+func TestTranscriptLogger(t *testing.T) {
+	assert := assert.New(t)
+
+	agent := queryFunc(func(q Query) (*QueryResponse, error) {
+		if q.Query[0] == "boom" {
+			return nil, errors.New("timeout asking for jane@example.com")
+		}
+		resp := &QueryResponse{SessionId: q.SessionId}
+		resp.Result.ResolvedQuery = q.Query[0]
+		resp.Result.Params = map[string]interface{}{"email": "jane@example.com"}
+		resp.Result.Contexts = []Context{{Name: "signup", Lifespan: 2, Params: map[string]interface{}{"email": "jane@example.com"}}}
+		resp.Result.Fulfillment = Fulfilment{Speech: "Thanks jane@example.com", Messages: []Message{
+			{Type: 0, Speech: "Thanks jane@example.com"},
+			{
+				Type:     1,
+				Title:    "jane@example.com",
+				Subtitle: "Call +34 600 123 456",
+				Buttons:  []CardButton{{Text: "Email jane@example.com", Postback: "mailto:jane@example.com"}},
+			},
+			{Type: 2, Replies: []string{"Yes", "Use jane@example.com"}},
+			{Type: 4, Payload: map[string]interface{}{"contact": map[string]interface{}{"email": "jane@example.com"}}},
+		}}
+		return resp, nil
+	})
+	sink := &memorySink{}
+	l := NewTranscriptLogger(agent, sink, "salt")
+
+	q := Query{
+		Query:           []string{"I'm jane@example.com"},
+		SessionId:       "s1",
+		Entities:        []UserEntity{{Name: "contact", Entries: []Entry{{Value: "jane@example.com", Synonyms: []string{"jane@example.com", "Jane"}}}}},
+		OriginalRequest: Platform{Source: "slack", Data: map[string]string{"user": "jane@example.com"}},
+	}
+	resp, err := l.Query(q)
+	assert.Nil(err)
+	assert.Equal("jane@example.com", resp.Result.Params["email"], "answers aren't redacted")
+	assert.Equal("I'm jane@example.com", q.Query[0], "queries aren't redacted")
+
+	_, err = l.Query(Query{Query: []string{"boom"}, SessionId: "s1"})
+	assert.Error(err)
+
+	records := *sink
+	assert.Len(records, 2)
+	assert.Equal(records[0].Session, records[1].Session)
+	assert.Len(records[0].Session, 32)
+	assert.NotEqual(l.hashSession("s1"), (&TranscriptLogger{Salt: "other"}).hashSession("s1"))
+	assert.Equal(records[0].Session, records[0].Request.SessionId)
+	assert.Equal(records[0].Session, records[0].Response.SessionId)
+	assert.Equal("I'm [REDACTED:email]", records[0].Request.Query[0])
+	assert.Equal("I'm [REDACTED:email]", records[0].Response.Result.ResolvedQuery)
+	assert.Equal("[REDACTED:email]", records[0].Response.Result.Params["email"])
+	assert.Equal("[REDACTED:email]", records[0].Response.Result.Contexts[0].Params["email"])
+	assert.Equal("Thanks [REDACTED:email]", records[0].Response.Result.Fulfillment.Messages[0].Speech)
+	assert.Equal(Entry{Value: "[REDACTED:email]", Synonyms: []string{"[REDACTED:email]", "Jane"}}, records[0].Request.Entities[0].Entries[0])
+	assert.Equal("[REDACTED:email]", records[0].Request.OriginalRequest.Data["user"])
+	card := records[0].Response.Result.Fulfillment.Messages[1]
+	assert.Equal("[REDACTED:email]", card.Title)
+	assert.Equal("Call [REDACTED:phone]", card.Subtitle)
+	assert.Equal([]CardButton{{Text: "Email [REDACTED:email]", Postback: "mailto:[REDACTED:email]"}}, card.Buttons)
+	assert.Equal([]string{"Yes", "Use [REDACTED:email]"}, records[0].Response.Result.Fulfillment.Messages[2].Replies)
+	assert.Equal(map[string]interface{}{"contact": map[string]interface{}{"email": "[REDACTED:email]"}}, records[0].Response.Result.Fulfillment.Messages[3].Payload)
+	assert.Equal("jane@example.com", resp.Result.Fulfillment.Messages[1].Title, "answers aren't redacted")
+	assert.Equal("jane@example.com", q.Entities[0].Entries[0].Value, "queries aren't redacted")
+	assert.Nil(records[1].Response)
+	assert.Equal("timeout asking for [REDACTED:email]", records[1].Error)
+
+	buf := new(bytes.Buffer)
+	assert.Nil(NewJSONLSink(buf).Write(records[1]))
+	var line map[string]interface{}
+	assert.Nil(json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal("timeout asking for [REDACTED:email]", line["error"])
+	assert.NotContains(buf.String(), "s1")
+}
@@ -20,7 +20,7 @@ func (c *ApiClient) Tts(text string) (string, error) {
 	req.Header.Set("Accept-Language", c.config.SpeechLang)
 
 	httpClient := http.DefaultClient
-	resp, err := httpClient.Do(req)
+	resp, err := c.doRequest(httpClient, req, "tts")
 	if err != nil {
 		return "", err
 	}
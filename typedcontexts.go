@@ -0,0 +1,136 @@
+package apiai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ContextParam returns the parameter name of ctx converted to T. Values that are
+// not already a T (e.g. float64 numbers decoded from JSON) are converted through
+// their JSON representation.
+func ContextParam[T any](ctx Context, name string) (T, error) {
+	var value T
+	raw, ok := ctx.Params[name]
+	if !ok {
+		return value, fmt.Errorf("apiai: parameter %q not found in context %q", name, ctx.Name)
+	}
+	if v, ok := raw.(T); ok {
+		return v, nil
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return value, fmt.Errorf("apiai: error on parameter %q, %v", name, err)
+	}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return value, fmt.Errorf("apiai: error on parameter %q, %v", name, err)
+	}
+	return value, nil
+}
+
+// FindContext looks up a context by name. api.ai lowercases context names in
+// query results, so names are compared case-insensitively.
+func FindContext(contexts []Context, name string) (*Context, bool) {
+	for i := range contexts {
+		if strings.EqualFold(contexts[i].Name, name) {
+			return &contexts[i], true
+		}
+	}
+	return nil, false
+}
+
+// ContextsActive reports whether every context in required is in active, as
+// the input contexts of an intent must be.
+func ContextsActive(required []string, active []Context) bool {
+	for _, name := range required {
+		if _, ok := FindContext(active, name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// SetContext returns contexts with c added or replacing the context with the
+// same name, or removing it when its lifespan is 0. contexts isn't modified.
+func SetContext(contexts []Context, c Context) []Context {
+	var updated []Context
+	for _, existing := range contexts {
+		if !strings.EqualFold(existing.Name, c.Name) {
+			updated = append(updated, existing)
+		}
+	}
+	if c.Lifespan > 0 {
+		updated = append(updated, c)
+	}
+	return updated
+}
+
+// DecayContexts returns the contexts still active after a query: every one
+// loses a unit of lifespan, and the ones reaching 0 expire.
+func DecayContexts(contexts []Context) []Context {
+	var remaining []Context
+	for _, c := range contexts {
+		if c.Lifespan--; c.Lifespan > 0 {
+			remaining = append(remaining, c)
+		}
+	}
+	return remaining
+}
+
+// ContextSchema declares a context whose parameters are described by the
+// struct P. P fields are mapped to parameters following their json tags.
+type ContextSchema[P any] struct {
+	Name     string
+	Lifespan int
+}
+
+func NewContextSchema[P any](name string, lifespan int) ContextSchema[P] {
+	return ContextSchema[P]{Name: name, Lifespan: lifespan}
+}
+
+// Context builds a Context ready to be sent with CreateContext or Query.
+func (s ContextSchema[P]) Context(params P) (Context, error) {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return Context{}, fmt.Errorf("apiai: error on context %q, %v", s.Name, err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return Context{}, fmt.Errorf("apiai: error on context %q, %v", s.Name, err)
+	}
+	return Context{Name: s.Name, Lifespan: s.Lifespan, Params: m}, nil
+}
+
+// Params decodes the parameters of ctx into P.
+func (s ContextSchema[P]) Params(ctx Context) (P, error) {
+	var params P
+	b, err := json.Marshal(ctx.Params)
+	if err != nil {
+		return params, fmt.Errorf("apiai: error on context %q, %v", s.Name, err)
+	}
+	if err := json.Unmarshal(b, &params); err != nil {
+		return params, fmt.Errorf("apiai: error on context %q, %v", s.Name, err)
+	}
+	return params, nil
+}
+
+// Lookup finds the schema context within contexts, typically Result.Contexts,
+// and decodes its parameters. The boolean reports whether the context is active.
+func (s ContextSchema[P]) Lookup(contexts []Context) (P, bool, error) {
+	var params P
+	ctx, ok := FindContext(contexts, s.Name)
+	if !ok {
+		return params, false, nil
+	}
+	params, err := s.Params(*ctx)
+	return params, true, err
+}
+
+// Create stores the context for the given session through c.CreateContext.
+func (s ContextSchema[P]) Create(c *ApiClient, params P, sessionId string) error {
+	ctx, err := s.Context(params)
+	if err != nil {
+		return err
+	}
+	return c.CreateContext(ctx, sessionId)
+}
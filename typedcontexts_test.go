@@ -0,0 +1,138 @@
+package apiai
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+type coffeeParams struct {
+	Type        string  `json:"type"`
+	Temperature string  `json:"temperature"`
+	Sugar       int     `json:"sugar"`
+	Price       float64 `json:"price,omitempty"`
+}
+
+func TestContextParam(t *testing.T) {
+	assert := assert.New(t)
+	ctx := Context{
+		Name: "coffee-time",
+		Params: map[string]interface{}{
+			"type":  "long",
+			"sugar": 2.0,
+			"duration": map[string]interface{}{
+				"amount": 30.0,
+				"unit":   "min",
+			},
+		},
+	}
+
+	s, err := ContextParam[string](ctx, "type")
+	assert.Nil(err)
+	assert.Equal("long", s)
+
+	n, err := ContextParam[int](ctx, "sugar")
+	assert.Nil(err)
+	assert.Equal(2, n)
+
+	d, err := ContextParam[struct {
+		Amount float64 `json:"amount"`
+		Unit   string  `json:"unit"`
+	}](ctx, "duration")
+	assert.Nil(err)
+	assert.Equal(30.0, d.Amount)
+	assert.Equal("min", d.Unit)
+
+	_, err = ContextParam[string](ctx, "milk")
+	assert.EqualError(err, `apiai: parameter "milk" not found in context "coffee-time"`)
+
+	_, err = ContextParam[int](ctx, "type")
+	assert.NotNil(err)
+}
+
+func TestSessionContexts(t *testing.T) {
+	assert := assert.New(t)
+	active := []Context{{Name: "ordering", Lifespan: 1}, {Name: "greeted", Lifespan: 3}}
+
+	assert.True(ContextsActive([]string{"Ordering", "greeted"}, active))
+	assert.False(ContextsActive([]string{"paying"}, active))
+
+	assert.Equal([]Context{{Name: "greeted", Lifespan: 3}, {Name: "Ordering", Lifespan: 5}}, SetContext(active, Context{Name: "Ordering", Lifespan: 5}))
+	assert.Equal([]Context{{Name: "ordering", Lifespan: 1}}, SetContext(active, Context{Name: "greeted"}), "lifespan 0 removes the context")
+	assert.Equal([]Context{{Name: "greeted", Lifespan: 2}}, DecayContexts(active))
+	assert.Equal(3, active[1].Lifespan, "contexts aren't modified")
+}
+
+func TestContextSchema(t *testing.T) {
+	assert := assert.New(t)
+	schema := NewContextSchema[coffeeParams]("Coffee-Time", 5)
+
+	ctx, err := schema.Context(coffeeParams{Type: "long", Temperature: "hot", Sugar: 1})
+	assert.Nil(err)
+	assert.Equal(Context{
+		Name:     "Coffee-Time",
+		Lifespan: 5,
+		Params:   map[string]interface{}{"type": "long", "temperature": "hot", "sugar": 1.0},
+	}, ctx)
+
+	tests := []struct {
+		description    string
+		contexts       []Context
+		expectedParams coffeeParams
+		expectedFound  bool
+	}{
+		{
+			description: "context is active, names are compared case-insensitively",
+			contexts: []Context{
+				{Name: "other", Params: map[string]interface{}{}},
+				{Name: "coffee-time", Lifespan: 4, Params: map[string]interface{}{"type": "short", "sugar": 3.0}},
+			},
+			expectedParams: coffeeParams{Type: "short", Sugar: 3},
+			expectedFound:  true,
+		}, {
+			description:    "context is not active",
+			contexts:       []Context{{Name: "other"}},
+			expectedParams: coffeeParams{},
+			expectedFound:  false,
+		},
+	}
+
+	for _, tc := range tests {
+		params, found, err := schema.Lookup(tc.contexts)
+
+		assert.Nil(err, tc.description)
+		assert.Equal(tc.expectedParams, params, tc.description)
+		assert.Equal(tc.expectedFound, found, tc.description)
+	}
+}
+
+func TestContextSchemaCreate(t *testing.T) {
+	c, err := NewClient(&ClientConfig{Token: "fakeToken"})
+	if err != nil {
+		t.FailNow()
+	}
+	assert := assert.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var sent Context
+	httpmock.RegisterResponder("POST", c.buildUrl("contexts", map[string]string{
+		"sessionId": "123454321",
+	}), func(req *http.Request) (*http.Response, error) {
+		b, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(b, &sent)
+		return httpmock.NewStringResponse(200, `{}`), nil
+	})
+
+	schema := NewContextSchema[coffeeParams]("coffee-time", 2)
+	err = schema.Create(c, coffeeParams{Type: "long", Temperature: "cold"}, "123454321")
+
+	assert.Nil(err)
+	assert.Equal("coffee-time", sent.Name)
+	assert.Equal(2, sent.Lifespan)
+	assert.Equal("cold", sent.Params["temperature"])
+}
@@ -0,0 +1,89 @@
+package apiai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+type UserEntity struct {
+	SessionId string  `json:"sessionId,omitempty"`
+	Name      string  `json:"name"`
+	Extend    bool    `json:"extend"`
+	Entries   []Entry `json:"entries"`
+}
+
+type userEntitiesRequest struct {
+	SessionId string       `json:"sessionId"`
+	Entities  []UserEntity `json:"entities"`
+}
+
+func (c *ApiClient) CreateUserEntities(entities []UserEntity, sessionId string) error {
+
+	resp, err := c.getApiaiResponse(http.MethodPost, "userEntities", map[string]string{"sessionId": sessionId}, userEntitiesRequest{sessionId, entities})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	default:
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+}
+
+func (c *ApiClient) GetUserEntity(name, sessionId string) (*UserEntity, error) {
+	resp, err := c.getApiaiResponse(http.MethodGet, "userEntities/"+url.QueryEscape(name), map[string]string{"sessionId": sessionId}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var entity *UserEntity
+	switch resp.StatusCode {
+	case http.StatusOK:
+		decoder := json.NewDecoder(resp.Body)
+		err = decoder.Decode(&entity)
+		if err != nil {
+			return nil, err
+		}
+		return entity, nil
+	default:
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+}
+
+func (c *ApiClient) UpdateUserEntity(name string, entity UserEntity, sessionId string) error {
+	entity.SessionId = sessionId
+
+	resp, err := c.getApiaiResponse(http.MethodPut, "userEntities/"+url.QueryEscape(name), map[string]string{"sessionId": sessionId}, entity)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	default:
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+}
+
+func (c *ApiClient) DeleteUserEntity(name, sessionId string) error {
+
+	resp, err := c.getApiaiResponse(http.MethodDelete, "userEntities/"+url.QueryEscape(name), map[string]string{"sessionId": sessionId}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	default:
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+}
@@ -0,0 +1,204 @@
+package apiai
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateUserEntities(t *testing.T) {
+	c, err := NewClient(&ClientConfig{Token: "fakeToken"})
+	if err != nil {
+		t.FailNow()
+	}
+	assert := assert.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	tests := []struct {
+		description   string
+		responder     httpmock.Responder
+		expectedError error
+	}{
+		{
+			description: "api ai success, no errors",
+			responder: func(req *http.Request) (*http.Response, error) {
+				b, _ := ioutil.ReadAll(req.Body)
+				var body map[string]interface{}
+				json.Unmarshal(b, &body)
+				if body["sessionId"] != "123454321" || len(body["entities"].([]interface{})) != 1 {
+					return httpmock.NewStringResponse(http.StatusBadRequest, `{}`), nil
+				}
+				return httpmock.NewStringResponse(200, `{"status": {"code": 200, "errorType": "success"}}`), nil
+			},
+			expectedError: nil,
+		}, {
+			description:   "api ai failed with an error 400",
+			responder:     httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
+			expectedError: &StatusError{StatusCode: 400},
+		},
+	}
+
+	for _, tc := range tests {
+		httpmock.RegisterResponder("POST", c.buildUrl("userEntities", map[string]string{
+			"sessionId": "123454321",
+		}), tc.responder)
+
+		err := c.CreateUserEntities([]UserEntity{
+			{
+				Name:   "Application",
+				Extend: true,
+				Entries: []Entry{
+					{Value: "Firefox", Synonyms: []string{"Firefox", "Mozilla"}},
+				},
+			},
+		}, "123454321")
+
+		assert.Equal(err, tc.expectedError, tc.description)
+
+		httpmock.Reset()
+	}
+}
+
+func TestGetUserEntity(t *testing.T) {
+	c, err := NewClient(&ClientConfig{Token: "fakeToken"})
+	if err != nil {
+		t.FailNow()
+	}
+	assert := assert.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	tests := []struct {
+		description      string
+		responder        httpmock.Responder
+		expectedResponse *UserEntity
+		expectedError    error
+	}{
+		{
+			description: "api ai success, no errors",
+			responder: httpmock.NewStringResponder(200, `{
+  "sessionId": "123454321",
+  "name": "Application",
+  "extend": false,
+  "entries": [
+    {
+      "value": "Firefox",
+      "synonyms": ["Firefox", "Mozilla"]
+    }
+  ]
+}`),
+			expectedResponse: &UserEntity{
+				SessionId: "123454321",
+				Name:      "Application",
+				Entries: []Entry{
+					{Value: "Firefox", Synonyms: []string{"Firefox", "Mozilla"}},
+				},
+			},
+			expectedError: nil,
+		}, {
+			description:      "api ai failed with an error 400",
+			responder:        httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
+			expectedResponse: nil,
+			expectedError:    &StatusError{StatusCode: 400},
+		},
+	}
+
+	for _, tc := range tests {
+		httpmock.RegisterResponder("GET", c.buildUrl("userEntities/Application", map[string]string{
+			"sessionId": "123454321",
+		}), tc.responder)
+
+		r, err := c.GetUserEntity("Application", "123454321")
+
+		assert.Equal(r, tc.expectedResponse, tc.description)
+		assert.Equal(err, tc.expectedError, tc.description)
+
+		httpmock.Reset()
+	}
+}
+
+func TestUpdateUserEntity(t *testing.T) {
+	c, err := NewClient(&ClientConfig{Token: "fakeToken"})
+	if err != nil {
+		t.FailNow()
+	}
+	assert := assert.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	tests := []struct {
+		description   string
+		responder     httpmock.Responder
+		expectedError error
+	}{
+		{
+			description:   "api ai success, no errors",
+			responder:     httpmock.NewStringResponder(200, `{}`),
+			expectedError: nil,
+		}, {
+			description:   "api ai failed with an error 400",
+			responder:     httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
+			expectedError: &StatusError{StatusCode: 400},
+		},
+	}
+
+	for _, tc := range tests {
+		httpmock.RegisterResponder("PUT", c.buildUrl("userEntities/Application", map[string]string{
+			"sessionId": "123454321",
+		}), tc.responder)
+
+		err := c.UpdateUserEntity("Application", UserEntity{
+			Name: "Application",
+			Entries: []Entry{
+				{Value: "Chrome", Synonyms: []string{"Chrome", "Google Chrome"}},
+			},
+		}, "123454321")
+
+		assert.Equal(err, tc.expectedError, tc.description)
+
+		httpmock.Reset()
+	}
+}
+
+func TestDeleteUserEntity(t *testing.T) {
+	c, err := NewClient(&ClientConfig{Token: "fakeToken"})
+	if err != nil {
+		t.FailNow()
+	}
+	assert := assert.New(t)
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	tests := []struct {
+		description   string
+		responder     httpmock.Responder
+		expectedError error
+	}{
+		{
+			description:   "api ai success, no errors",
+			responder:     httpmock.NewStringResponder(200, `{}`),
+			expectedError: nil,
+		}, {
+			description:   "api ai failed with an error 400",
+			responder:     httpmock.NewStringResponder(http.StatusBadRequest, `{}`),
+			expectedError: &StatusError{StatusCode: 400},
+		},
+	}
+
+	for _, tc := range tests {
+		httpmock.RegisterResponder("DELETE", c.buildUrl("userEntities/Application", map[string]string{
+			"sessionId": "123454321",
+		}), tc.responder)
+
+		err := c.DeleteUserEntity("Application", "123454321")
+
+		assert.Equal(err, tc.expectedError, tc.description)
+
+		httpmock.Reset()
+	}
+}